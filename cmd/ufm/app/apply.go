@@ -0,0 +1,103 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openbce/kperf/pkg/ufm"
+	"github.com/openbce/kperf/pkg/ufm/manifest"
+)
+
+type applyCmdOptions struct {
+	Filename string
+	DryRun   bool
+}
+
+var applyCmdOpt = applyCmdOptions{}
+
+// applyCmd represents the apply command
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Apply the IB networks in the manifest to UFM",
+	Long: `Apply the IB networks in the YAML or JSON manifest to UFM; the manifest is compared with
+the IB networks in UFM, and only the needed GUID and QoS changes are applied after printing the plan.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ibs, err := manifest.LoadFile(applyCmdOpt.Filename)
+		if err != nil {
+			fmt.Printf("Failed to load manifest: %v\n", err)
+			os.Exit(1)
+		}
+
+		ufmClient, err := newUFM()
+		if err != nil {
+			fmt.Printf("Failed to connect to UFM: %v\n", err)
+			os.Exit(1)
+		}
+
+		ctx, cancel := newContext()
+		defer cancel()
+
+		var plans []*ufm.ApplyPlan
+		for _, ib := range ibs {
+			plan, ufmErr := ufmClient.PlanWithContext(ctx, ib)
+			if ufmErr != nil {
+				fmt.Printf("Failed to plan IB network 0x%04x: %v\n", ib.PKey, ufmErr)
+				os.Exit(1)
+			}
+			plans = append(plans, plan)
+			printPlan(plan)
+		}
+
+		if applyCmdOpt.DryRun {
+			return
+		}
+
+		for _, plan := range plans {
+			if plan.IsEmpty() {
+				continue
+			}
+			if ufmErr := ufmClient.ApplyWithContext(ctx, plan); ufmErr != nil {
+				fmt.Printf("Failed to apply IB network 0x%04x: %v\n", plan.Desired.PKey, ufmErr)
+				os.Exit(1)
+			}
+			fmt.Printf("IB network 0x%04x applied.\n", plan.Desired.PKey)
+		}
+	},
+}
+
+func printPlan(plan *ufm.ApplyPlan) {
+	fmt.Printf("IB network %q (0x%04x):\n", plan.Desired.Name, plan.Desired.PKey)
+	if plan.IsEmpty() {
+		fmt.Printf("    no changes\n")
+		return
+	}
+	for _, step := range plan.Steps {
+		fmt.Printf("    %s\n", step)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+
+	applyCmd.Flags().StringVarP(&applyCmdOpt.Filename, "filename", "f", "", "The manifest of the IB networks, '-' means stdin.")
+	applyCmd.MarkFlagRequired("filename")
+	applyCmd.Flags().BoolVar(&applyCmdOpt.DryRun, "dry-run", false, "Only print the plan without applying it.")
+}
@@ -0,0 +1,216 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openbce/kperf/pkg/ufm"
+	"github.com/openbce/kperf/pkg/ufm/ufmconfig"
+)
+
+type configCmdOptions struct {
+	Context    ufmconfig.Context
+	Credential ufmconfig.Credential
+}
+
+var configCmdOpt = configCmdOptions{}
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the contexts of UFM in the config file",
+	Long: `Manage the contexts of UFM in the config file, which is $UFM_CONFIG or ~/.ufm/config by default;
+the environment values, e.g. UFM_ADDRESS, take precedence over the context.`,
+}
+
+var useContextCmd = &cobra.Command{
+	Use:   "use-context NAME",
+	Short: "Set the current context",
+	Long:  `Set the current context`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path, conf := loadConfigFile()
+		if err := conf.UseContext(args[0]); err != nil {
+			fmt.Printf("Failed to use context: %v\n", err)
+			os.Exit(1)
+		}
+		saveConfigFile(path, conf)
+		fmt.Printf("Switched to context %q.\n", args[0])
+	},
+}
+
+var getContextsCmd = &cobra.Command{
+	Use:   "get-contexts",
+	Short: "List the contexts in the config file",
+	Long:  `List the contexts in the config file`,
+	Run: func(cmd *cobra.Command, args []string) {
+		_, conf := loadConfigFile()
+
+		fmt.Printf("%-10s%-20s%-30s%-10s%-20s\n", "Current", "Name", "Address", "Schema", "Credential")
+		for _, c := range conf.Contexts {
+			current := ""
+			if c.Name == conf.CurrentContext {
+				current = "*"
+			}
+			fmt.Printf("%-10s%-20s%-30s%-10s%-20s\n", current, c.Name, contextAddress(&c.Context), c.Context.Schema, c.Context.Credential)
+		}
+	},
+}
+
+var setContextCmd = &cobra.Command{
+	Use:   "set-context NAME",
+	Short: "Create or update a context",
+	Long:  `Create or update a context; only the provided flags are updated for an existing context.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path, conf := loadConfigFile()
+
+		ctx := ufmconfig.Context{}
+		if cur, found := conf.Context(args[0]); found {
+			ctx = *cur
+		}
+		flags := cmd.Flags()
+		if flags.Changed("address") {
+			ctx.Address = configCmdOpt.Context.Address
+		}
+		if flags.Changed("port") {
+			ctx.Port = configCmdOpt.Context.Port
+		}
+		if flags.Changed("schema") {
+			ctx.Schema = configCmdOpt.Context.Schema
+		}
+		if flags.Changed("credential") {
+			ctx.Credential = configCmdOpt.Context.Credential
+		}
+		if flags.Changed("certificate-authority") {
+			ctx.CertificateAuthority = configCmdOpt.Context.CertificateAuthority
+		}
+		if flags.Changed("insecure-skip-verify") {
+			ctx.InsecureSkipVerify = configCmdOpt.Context.InsecureSkipVerify
+		}
+
+		if err := validateContext(&ctx); err != nil {
+			fmt.Printf("Failed to set context %q: %v\n", args[0], err)
+			os.Exit(1)
+		}
+
+		conf.SetContext(args[0], ctx)
+		saveConfigFile(path, conf)
+		fmt.Printf("Context %q set.\n", args[0])
+	},
+}
+
+var setCredentialsCmd = &cobra.Command{
+	Use:   "set-credentials NAME",
+	Short: "Create or update a credential",
+	Long:  `Create or update a credential; only the provided flags are updated for an existing credential.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path, conf := loadConfigFile()
+
+		cred := ufmconfig.Credential{}
+		if cur, found := conf.Credential(args[0]); found {
+			cred = *cur
+		}
+		flags := cmd.Flags()
+		if flags.Changed("auth-type") {
+			if _, err := ufm.ParseAuthType(configCmdOpt.Credential.AuthType); err != nil {
+				fmt.Printf("Failed to set credential %q: %v\n", args[0], err)
+				os.Exit(1)
+			}
+			cred.AuthType = configCmdOpt.Credential.AuthType
+		}
+		if flags.Changed("username") {
+			cred.Username = configCmdOpt.Credential.Username
+		}
+		if flags.Changed("password") {
+			cred.Password = configCmdOpt.Credential.Password
+		}
+		if flags.Changed("token") {
+			cred.Token = configCmdOpt.Credential.Token
+		}
+
+		conf.SetCredential(args[0], cred)
+		saveConfigFile(path, conf)
+		fmt.Printf("Credential %q set.\n", args[0])
+	},
+}
+
+func loadConfigFile() (string, *ufmconfig.Config) {
+	path, err := ufmconfig.DefaultPath()
+	if err != nil {
+		fmt.Printf("Failed to locate config file: %v\n", err)
+		os.Exit(1)
+	}
+
+	conf, err := ufmconfig.Load(path)
+	if err != nil {
+		fmt.Printf("Failed to load config file: %v\n", err)
+		os.Exit(1)
+	}
+
+	return path, conf
+}
+
+func saveConfigFile(path string, conf *ufmconfig.Config) {
+	if err := conf.Save(path); err != nil {
+		fmt.Printf("Failed to save config file: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func validateContext(ctx *ufmconfig.Context) error {
+	if ctx.Address == "" {
+		return fmt.Errorf("address is required")
+	}
+	if ctx.Schema != "" && ctx.Schema != "http" && ctx.Schema != "https" {
+		return fmt.Errorf("schema %q is not one of 'http' or 'https'", ctx.Schema)
+	}
+	if ctx.Port < 0 || ctx.Port > 65535 {
+		return fmt.Errorf("port %d is out of range 1 - 65535", ctx.Port)
+	}
+
+	return nil
+}
+
+func contextAddress(ctx *ufmconfig.Context) string {
+	if ctx.Port == 0 {
+		return ctx.Address
+	}
+	return fmt.Sprintf("%s:%d", ctx.Address, ctx.Port)
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(useContextCmd, getContextsCmd, setContextCmd, setCredentialsCmd)
+
+	setContextCmd.Flags().StringVar(&configCmdOpt.Context.Address, "address", "", "IP address or hostname of ufm server.")
+	setContextCmd.Flags().IntVar(&configCmdOpt.Context.Port, "port", 0, "REST API port of ufm.")
+	setContextCmd.Flags().StringVar(&configCmdOpt.Context.Schema, "schema", "", "http or https.")
+	setContextCmd.Flags().StringVar(&configCmdOpt.Context.Credential, "credential", "", "The name of the credential to connect to ufm.")
+	setContextCmd.Flags().StringVar(&configCmdOpt.Context.CertificateAuthority, "certificate-authority", "", "The path of the CA bundle of ufm.")
+	setContextCmd.Flags().BoolVar(&configCmdOpt.Context.InsecureSkipVerify, "insecure-skip-verify", false, "Skip the verification of ufm certificate.")
+
+	setCredentialsCmd.Flags().StringVar(&configCmdOpt.Credential.AuthType, "auth-type", "", "The authentication type, one of 'basic', 'token' or 'session'.")
+	setCredentialsCmd.Flags().StringVar(&configCmdOpt.Credential.Username, "username", "", "Username of ufm.")
+	setCredentialsCmd.Flags().StringVar(&configCmdOpt.Credential.Password, "password", "", "Password of ufm.")
+	setCredentialsCmd.Flags().StringVar(&configCmdOpt.Credential.Token, "token", "", "Access token of ufm.")
+}
@@ -27,6 +27,7 @@ import (
 
 type createCmdOptions struct {
 	ufm.IBNetwork
+	Wait bool
 }
 
 var createCmdOpt = createCmdOptions{}
@@ -37,35 +38,57 @@ var createCmd = &cobra.Command{
 	Short: "Create an IB network in UFM",
 	Long:  `Create an IB network in UFM`,
 	Run: func(cmd *cobra.Command, args []string) {
-		ufmClient, err := ufm.NewUFM()
+		ufmClient, err := newUFM()
 		if err != nil {
 			fmt.Printf("Failed to connect to UFM: %v\n", err)
 			os.Exit(1)
 		}
 
-		if err := ufmClient.CreateIBNetwork(&createCmdOpt.IBNetwork); err != nil {
+		if err := parseGUIDMembers(&createCmdOpt.IBNetwork); err != nil {
 			fmt.Printf("Failed to create IB network in UFM: %v\n", err)
 			os.Exit(1)
 		}
 
-		if err := ufmClient.Patch(&createCmdOpt.IBNetwork, ufm.QoSField, ufm.SetStrategy); err != nil {
-			fmt.Printf("Failed to update IB network QoS in UFM: %v\n", err)
+		ctx, cancel := newContext()
+		defer cancel()
+
+		if err := ufmClient.CreateIBNetworkWithContext(ctx, &createCmdOpt.IBNetwork, jobOptions(createCmdOpt.Wait)...); err != nil {
+			fmt.Printf("Failed to create IB network in UFM: %v\n", err)
 			os.Exit(1)
 		}
+
+		fmt.Printf("IB network 0x%04x created.\n", createCmdOpt.PKey)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(createCmd)
 
-	createCmd.Flags().Int32Var(&createCmdOpt.PKey, "pkey", 0, "The pkeys for IB network.")
-	createCmd.MarkFlagRequired("pkey")
+	createCmd.Flags().Int32Var(&createCmdOpt.PKey, "pkey", 0, "The pkeys for IB network; a free one is allocated if not provided, see UFM_PKEY_RANGE.")
 	createCmd.Flags().BoolVar(&createCmdOpt.EnableSharp, "enable-sharp", false, "Create sharp allocation accordingly")
-	createCmd.Flags().StringSliceVar(&createCmdOpt.GUIDs, "guids", []string{}, "The GUID list of the IB network.")
+	createCmd.Flags().StringSliceVar(&createCmdOpt.GUIDs, "guids", []string{}, "The GUID list of the IB network, each GUID may have a membership, e.g. <guid>:limited; default to full.")
 	createCmd.MarkFlagRequired("guids")
 	createCmd.Flags().Int32Var(&createCmdOpt.MTU, "mtu", 2048, "The MTU of the services, one of 2k or 4k.")
 	createCmd.Flags().BoolVar(&createCmdOpt.IPOverIB, "ip-over-ib", true, "Enable IPoIB.")
 	createCmd.Flags().BoolVar(&createCmdOpt.Index0, "index0", false, "Store the PKey at index 0 of the PKey table of the GUID.")
 	createCmd.Flags().Int32Var(&createCmdOpt.ServiceLevel, "service-level", 0, "The service level of IB network, value can be range from 0-15")
+	createCmd.Flags().BoolVar(&createCmdOpt.Wait, "wait", true, "Wait for the jobs of UFM to finish; --wait=false returns once the jobs are accepted.")
 	createCmd.Flags().Float64Var(&createCmdOpt.RateLimit, "rate-limit", 2.5, "The rate limit of IB network, can be one of the following: 2.5, 10, 30, 5, 20, 40, 60, 80, 120, 14, 56, 112, 168, 25, 100, 200, or 300")
 }
+
+// parseGUIDMembers parses the GUIDs with membership, e.g. <guid>:limited, into the members of the IB network.
+func parseGUIDMembers(ib *ufm.IBNetwork) error {
+	var guids []string
+	for _, g := range ib.GUIDs {
+		member, err := ufm.ParseGUIDMember(g)
+		if err != nil {
+			return err
+		}
+		member.Index0 = ib.Index0
+		guids = append(guids, member.GUID)
+		ib.Members = append(ib.Members, member)
+	}
+	ib.GUIDs = guids
+
+	return nil
+}
@@ -38,19 +38,22 @@ var deleteCmd = &cobra.Command{
 	Short: "Delete IB network from UFM",
 	Long:  `Delete IB network from UFM`,
 	Run: func(cmd *cobra.Command, args []string) {
-		ufmClient, err := ufm.NewUFM()
+		ufmClient, err := newUFM()
 		if err != nil {
 			fmt.Printf("Failed to connect to UFM: %v\n", err)
 			os.Exit(1)
 		}
 
+		ctx, cancel := newContext()
+		defer cancel()
+
 		pkey, err := ufm.ParsePkey(deleteCmdOpt.PKeyStr)
 		if err != nil {
 			fmt.Printf("Failed to delete IB network from UFM: %v\n", err)
 			os.Exit(1)
 		}
 
-		if err := ufmClient.DeleteIBNetwork(pkey); err != nil {
+		if err := ufmClient.DeleteIBNetworkWithContext(ctx, pkey); err != nil {
 			fmt.Printf("Failed to delete IB network from UFM: %v\n", err)
 			os.Exit(1)
 		}
@@ -61,5 +64,5 @@ func init() {
 	rootCmd.AddCommand(deleteCmd)
 
 	deleteCmd.Flags().StringVar(&deleteCmdOpt.PKeyStr, "pkey", "", "The pkeys of IB network.")
-	createCmd.MarkFlagRequired("pkey")
+	deleteCmd.MarkFlagRequired("pkey")
 }
@@ -0,0 +1,220 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openbce/kperf/pkg/ufm"
+	"github.com/openbce/kperf/pkg/ufm/manifest"
+	"github.com/openbce/kperf/pkg/ufm/printer"
+)
+
+type diffCmdOptions struct {
+	Output string
+}
+
+var diffCmdOpt = diffCmdOptions{}
+
+// diffSource is the IB networks to compare, e.g. the live UFM or a manifest.
+type diffSource struct {
+	ibs []*ufm.IBNetwork
+	// The manifests only cover some pkeys; the other pkeys are not compared.
+	partial bool
+}
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff FROM [TO]",
+	Short: "Compare the IB networks between UFM, manifests and snapshots",
+	Long: `Compare the IB networks of FROM with the ones of TO by pkey; TO is the live UFM by default.
+FROM and TO are one of:
+
+  live                 the IB networks in the UFM of the current context
+  context:<name>       the IB networks in the UFM of the context in the config file
+  manifest:<path>      the IB networks in the manifest of 'ufm apply'
+  snapshot:<path>      the IB networks in the snapshot of 'ufm export'
+  <path>               a manifest or snapshot, detected by its content
+
+Only the pkeys in the manifest are compared if one of them is a manifest. The command exits
+with 1 if there are differences, and 2 on errors, e.g. for CI to gate on the drift.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := printer.ParseOutput(diffCmdOpt.Output)
+		if diffCmdOpt.Output != "" && format != printer.JSONFormat && format != printer.YAMLFormat {
+			fmt.Printf("Failed to diff IB networks: unknown output format %q, one of json or yaml\n", diffCmdOpt.Output)
+			os.Exit(2)
+		}
+
+		ctx, cancel := newContext()
+		defer cancel()
+
+		to := "live"
+		if len(args) == 2 {
+			to = args[1]
+		}
+		fromSrc := loadDiffSource(ctx, args[0])
+		toSrc := loadDiffSource(ctx, to)
+
+		fromIBs, toIBs := fromSrc.ibs, toSrc.ibs
+		if fromSrc.partial || toSrc.partial {
+			pkeys := map[int32]bool{}
+			for _, src := range []*diffSource{fromSrc, toSrc} {
+				if src.partial {
+					for _, ib := range src.ibs {
+						pkeys[ib.PKey] = true
+					}
+				}
+			}
+			fromIBs, toIBs = filterIBNetworks(fromIBs, pkeys), filterIBNetworks(toIBs, pkeys)
+		}
+
+		diffs := ufm.DiffIBNetworks(fromIBs, toIBs)
+		if diffCmdOpt.Output != "" {
+			if diffs == nil {
+				diffs = []*ufm.IBNetworkDiff{}
+			}
+			if err := newPrinter(diffCmdOpt.Output).Print(os.Stdout, diffs); err != nil {
+				fmt.Printf("Failed to print output: %v\n", err)
+				os.Exit(2)
+			}
+		} else {
+			printDiffs(diffs)
+		}
+
+		if len(diffs) != 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func loadDiffSource(ctx context.Context, spec string) *diffSource {
+	kind, arg := spec, ""
+	if parts := strings.SplitN(spec, ":", 2); len(parts) == 2 {
+		kind, arg = parts[0], parts[1]
+	}
+
+	var src *diffSource
+	var err error
+	switch kind {
+	case "live":
+		src, err = loadLiveSource(ctx, "", true)
+	case "context":
+		src, err = loadLiveSource(ctx, arg, false)
+	case "manifest":
+		var ibs []*ufm.IBNetwork
+		ibs, err = manifest.LoadFile(arg)
+		src = &diffSource{ibs: ibs, partial: true}
+	case "snapshot":
+		var snapshot *ufm.Snapshot
+		if snapshot, err = manifest.LoadSnapshotFile(arg); err == nil {
+			src = &diffSource{ibs: snapshot.Partitions}
+		}
+	default:
+		src, err = loadFileSource(spec)
+	}
+	if err != nil {
+		fmt.Printf("Failed to load IB networks of %s: %v\n", spec, err)
+		os.Exit(2)
+	}
+
+	return src
+}
+
+func loadLiveSource(ctx context.Context, name string, withEnv bool) (*diffSource, error) {
+	if name == "" && !withEnv {
+		return nil, fmt.Errorf("empty context name")
+	}
+	if withEnv {
+		name = rootCmdOpt.Context
+	}
+
+	ufmClient, err := newUFMForContext(name, withEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	ibs, ufmErr := ufmClient.ListIBNetworkWithContext(ctx)
+	if ufmErr != nil {
+		return nil, ufmErr
+	}
+
+	return &diffSource{ibs: ibs}, nil
+}
+
+// loadFileSource reads the snapshot or manifest by its content.
+func loadFileSource(path string) (*diffSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if snapshot, err := manifest.LoadSnapshot(data); err == nil {
+		return &diffSource{ibs: snapshot.Partitions}, nil
+	}
+
+	ibs, err := manifest.Load(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	return &diffSource{ibs: ibs, partial: true}, nil
+}
+
+func filterIBNetworks(ibs []*ufm.IBNetwork, pkeys map[int32]bool) []*ufm.IBNetwork {
+	var res []*ufm.IBNetwork
+	for _, ib := range ibs {
+		if pkeys[ib.PKey] {
+			res = append(res, ib)
+		}
+	}
+	return res
+}
+
+func printDiffs(diffs []*ufm.IBNetworkDiff) {
+	for _, d := range diffs {
+		switch d.Type {
+		case ufm.DiffAdded:
+			fmt.Printf("+ 0x%04x %s\n", d.PKey, d.Name)
+		case ufm.DiffRemoved:
+			fmt.Printf("- 0x%04x %s\n", d.PKey, d.Name)
+		default:
+			fmt.Printf("~ 0x%04x %s\n", d.PKey, d.Name)
+		}
+		for _, f := range d.Fields {
+			fmt.Printf("    %s: %s -> %s\n", f.Field, f.From, f.To)
+		}
+		for _, g := range d.GUIDsAdded {
+			fmt.Printf("    + guid %s\n", g)
+		}
+		for _, g := range d.GUIDsRemoved {
+			fmt.Printf("    - guid %s\n", g)
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().StringVarP(&diffCmdOpt.Output, "output", "o", "", "Output format, one of json or yaml; default to the human readable diff.")
+}
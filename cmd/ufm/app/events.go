@@ -0,0 +1,186 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openbce/kperf/pkg/ufm"
+)
+
+type eventsCmdOptions struct {
+	Alarms   bool
+	Since    string
+	Until    string
+	Severity string
+	Object   string
+	Category string
+	Follow   bool
+	Interval time.Duration
+	Output   string
+}
+
+var eventsCmdOpt = eventsCmdOptions{}
+
+// eventsCmd represents the events command
+var eventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "List the events or alarms in UFM",
+	Long: `List the events in UFM, or the alarms with --alarms, filtered by severity, time window, object
+and category; with --follow, UFM is polled for the new events until interrupted.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		p := newPrinter(eventsCmdOpt.Output)
+
+		query, err := buildEventQuery()
+		if err != nil {
+			fmt.Printf("Failed to list events in UFM: %v\n", err)
+			os.Exit(1)
+		}
+		if eventsCmdOpt.Alarms && eventsCmdOpt.Follow {
+			fmt.Printf("Failed to list alarms in UFM: --follow is only supported for the events\n")
+			os.Exit(1)
+		}
+		if eventsCmdOpt.Follow && eventsCmdOpt.Interval <= 0 {
+			fmt.Printf("Failed to list events in UFM: invalid interval %v, it must be positive\n", eventsCmdOpt.Interval)
+			os.Exit(1)
+		}
+
+		ufmClient, err := newUFM()
+		if err != nil {
+			fmt.Printf("Failed to connect to UFM: %v\n", err)
+			os.Exit(1)
+		}
+
+		ctx, cancel := newContext()
+		defer cancel()
+
+		if eventsCmdOpt.Alarms {
+			alarms, ufmErr := ufmClient.ListAlarmsWithContext(ctx, query)
+			if ufmErr != nil {
+				fmt.Printf("Failed to list alarms in UFM: %v\n", ufmErr)
+				os.Exit(1)
+			}
+			printObject(p, alarms)
+			return
+		}
+
+		// The events seen at or after query.Since, to skip them in the next polls; the events
+		// with an invalid timestamp are always kept.
+		seen := map[int64]time.Time{}
+		for first := true; ; first = false {
+			events, ufmErr := ufmClient.ListEventsWithContext(ctx, query)
+			if ufmErr != nil {
+				if first || ctx.Err() != nil {
+					fmt.Printf("Failed to list events in UFM: %v\n", ufmErr)
+					os.Exit(1)
+				}
+				fmt.Fprintf(os.Stderr, "Failed to list events in UFM: %v\n", ufmErr)
+			}
+
+			fresh := []*ufm.IBEvent{}
+			for _, e := range events {
+				if _, found := seen[e.ID]; found {
+					continue
+				}
+				t, _ := e.Time()
+				seen[e.ID] = t
+				fresh = append(fresh, e)
+			}
+			sort.SliceStable(fresh, func(i, j int) bool { return fresh[i].ID < fresh[j].ID })
+
+			if first || len(fresh) != 0 {
+				printStream(p, eventsCmdOpt.Output, fresh, !first)
+			}
+			if !eventsCmdOpt.Follow {
+				return
+			}
+
+			// Only poll the events since the latest one, whose time may be shared by the next events.
+			for _, t := range seen {
+				if t.After(query.Since) {
+					query.Since = t
+				}
+			}
+			for id, t := range seen {
+				if !t.IsZero() && t.Before(query.Since) {
+					delete(seen, id)
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(eventsCmdOpt.Interval):
+			}
+		}
+	},
+}
+
+func buildEventQuery() (*ufm.EventQuery, error) {
+	query := &ufm.EventQuery{
+		Object:   eventsCmdOpt.Object,
+		Category: eventsCmdOpt.Category,
+	}
+
+	var err error
+	if query.MinSeverity, err = ufm.ParseSeverity(eventsCmdOpt.Severity); err != nil {
+		return nil, err
+	}
+	if query.Since, err = parseEventTime(eventsCmdOpt.Since); err != nil {
+		return nil, err
+	}
+	if query.Until, err = parseEventTime(eventsCmdOpt.Until); err != nil {
+		return nil, err
+	}
+
+	return query, nil
+}
+
+// parseEventTime parses the time as a duration before now, e.g. 1h, or in RFC3339.
+func parseEventTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, expected a duration, e.g. 1h, or RFC3339", s)
+	}
+
+	return t, nil
+}
+
+func init() {
+	rootCmd.AddCommand(eventsCmd)
+
+	eventsCmd.Flags().BoolVar(&eventsCmdOpt.Alarms, "alarms", false, "List the alarms instead of the events.")
+	eventsCmd.Flags().StringVar(&eventsCmdOpt.Since, "since", "", "Only list the events since the time, a duration before now, e.g. 1h, or RFC3339.")
+	eventsCmd.Flags().StringVar(&eventsCmdOpt.Until, "until", "", "Only list the events until the time, a duration before now, e.g. 10m, or RFC3339.")
+	eventsCmd.Flags().StringVar(&eventsCmdOpt.Severity, "severity", "", "The lowest severity of the events, one of 'Info', 'Warning', 'Minor' or 'Critical'.")
+	eventsCmd.Flags().StringVar(&eventsCmdOpt.Object, "object", "", "The object of the events, e.g. a port GUID or a system name.")
+	eventsCmd.Flags().StringVar(&eventsCmdOpt.Category, "category", "", "The category of the events, e.g. Hardware.")
+	eventsCmd.Flags().BoolVar(&eventsCmdOpt.Follow, "follow", false, "Poll UFM for the new events until interrupted.")
+	eventsCmd.Flags().DurationVar(&eventsCmdOpt.Interval, "interval", 10*time.Second, "The interval to poll the events with --follow, which must be positive.")
+	addOutputFlag(eventsCmd, &eventsCmdOpt.Output)
+}
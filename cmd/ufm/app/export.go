@@ -0,0 +1,91 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openbce/kperf/pkg/ufm/printer"
+)
+
+type exportCmdOptions struct {
+	Output   string
+	Filename string
+}
+
+var exportCmdOpt = exportCmdOptions{}
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export all the partitions in UFM as a snapshot",
+	Long: `Export all the partitions in UFM, including name, pkey, QoS, IPoIB and the membership of GUIDs,
+as a versioned snapshot, which can be restored by 'ufm restore'.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := printer.ParseOutput(exportCmdOpt.Output)
+		if format != printer.YAMLFormat && format != printer.JSONFormat {
+			fmt.Printf("Failed to export partitions: unknown output format %q, one of yaml or json\n", exportCmdOpt.Output)
+			os.Exit(1)
+		}
+		p := newPrinter(exportCmdOpt.Output)
+
+		ufmClient, err := newUFM()
+		if err != nil {
+			fmt.Printf("Failed to connect to UFM: %v\n", err)
+			os.Exit(1)
+		}
+
+		ctx, cancel := newContext()
+		defer cancel()
+
+		snapshot, ufmErr := ufmClient.ExportWithContext(ctx)
+		if ufmErr != nil {
+			fmt.Printf("Failed to export partitions: %v\n", ufmErr)
+			os.Exit(1)
+		}
+
+		var w io.Writer = os.Stdout
+		if exportCmdOpt.Filename != "" {
+			f, err := os.OpenFile(exportCmdOpt.Filename, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+			if err != nil {
+				fmt.Printf("Failed to export partitions: %v\n", err)
+				os.Exit(1)
+			}
+			defer f.Close()
+			w = f
+		}
+
+		if err := p.Print(w, snapshot); err != nil {
+			fmt.Printf("Failed to export partitions: %v\n", err)
+			os.Exit(1)
+		}
+		if exportCmdOpt.Filename != "" {
+			fmt.Printf("%d partitions exported to %s.\n", len(snapshot.Partitions), exportCmdOpt.Filename)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().StringVarP(&exportCmdOpt.Output, "output", "o", "yaml", "Output format, one of yaml or json.")
+	exportCmd.Flags().StringVarP(&exportCmdOpt.Filename, "filename", "f", "", "The file to write the snapshot; default to stdout.")
+}
@@ -21,45 +21,45 @@ import (
 	"os"
 
 	"github.com/spf13/cobra"
-
-	"github.com/openbce/kperf/pkg/ufm"
 )
 
+type listCmdOptions struct {
+	Output string
+}
+
+var listCmdOpt = listCmdOptions{}
+
 // listCmd represents the list command
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all IB network in UFM",
 	Long:  `List all IB network in UFM`,
 	Run: func(cmd *cobra.Command, args []string) {
-		ufmClient, err := ufm.NewUFM()
+		p := newPrinter(listCmdOpt.Output)
+
+		ufmClient, err := newUFM()
 		if err != nil {
 			fmt.Printf("Failed to connect to UFM: %v\n", err)
 			os.Exit(1)
 		}
-		ibs, ufmErr := ufmClient.ListIBNetwork()
+
+		ctx, cancel := newContext()
+		defer cancel()
+		ibs, ufmErr := ufmClient.ListIBNetworkWithContext(ctx)
 		if ufmErr != nil {
 			fmt.Printf("Failed to list IB network in UFM: %v\n", ufmErr)
 			os.Exit(1)
 		}
 
-		fmt.Printf("%-15s%-10s%-10s%-10s%-10s%-10s%-10s%-10s\n", "Name", "PKey", "Sharp", "IPoIB", "MTU", "Rate", "Level", "GUID#")
-		for _, ib := range ibs {
-			fmt.Printf("%-15s0x%04x    %-10t%-10t%-10d%-10.2f%-10d%-10d\n",
-				ib.Name,
-				ib.PKey,
-				ib.EnableSharp,
-				ib.IPOverIB,
-				ib.MTU,
-				ib.RateLimit,
-				ib.ServiceLevel,
-				len(ib.GUIDs))
-		}
+		printObject(p, ibs)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(listCmd)
 
+	addOutputFlag(listCmd, &listCmdOpt.Output)
+
 	// TODO(k82cn): add filters
 	// listCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
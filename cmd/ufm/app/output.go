@@ -0,0 +1,69 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openbce/kperf/pkg/ufm/printer"
+)
+
+// addOutputFlag adds the -o/--output flag of the read commands.
+func addOutputFlag(cmd *cobra.Command, output *string) {
+	cmd.Flags().StringVarP(output, "output", "o", "", fmt.Sprintf("Output format, one of %s.", printer.Formats))
+}
+
+// newPrinter creates the printer of the output flag, and exits if the format is invalid.
+func newPrinter(output string) printer.Printer {
+	p, err := printer.NewPrinter(output)
+	if err != nil {
+		fmt.Printf("Failed to parse output: %v\n", err)
+		os.Exit(1)
+	}
+
+	return p
+}
+
+func printObject(p printer.Printer, obj interface{}) {
+	if err := p.Print(os.Stdout, obj); err != nil {
+		fmt.Printf("Failed to print output: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// printStream prints the objects of a stream, e.g. the new events of each poll; the header of
+// the table is skipped except the first time.
+func printStream(p printer.Printer, output string, obj interface{}, skipHeader bool) {
+	if !skipHeader || !printer.IsTable(output) {
+		printObject(p, obj)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := p.Print(&buf, obj); err != nil {
+		fmt.Printf("Failed to print output: %v\n", err)
+		os.Exit(1)
+	}
+	if _, rows, found := strings.Cut(buf.String(), "\n"); found {
+		fmt.Print(rows)
+	}
+}
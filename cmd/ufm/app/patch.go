@@ -19,6 +19,7 @@ package app
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -30,6 +31,8 @@ type patchCmdOptions struct {
 	PkeyString  string
 	FieldStr    string
 	StrategyStr string
+	Wait        bool
+	AllowEmpty  bool
 }
 
 var patchCmdOpt = patchCmdOptions{}
@@ -40,12 +43,15 @@ var patchCmd = &cobra.Command{
 	Short: "Patch IB network in UFM",
 	Long:  `Patch IB network in UFM`,
 	Run: func(cmd *cobra.Command, args []string) {
-		ufmClient, err := ufm.NewUFM()
+		ufmClient, err := newUFM()
 		if err != nil {
 			fmt.Printf("Failed to connect to UFM: %v\n", err)
 			os.Exit(1)
 		}
 
+		ctx, cancel := newContext()
+		defer cancel()
+
 		field := ufm.ParseField(patchCmdOpt.FieldStr)
 		if field == ufm.UnknownField {
 			fmt.Printf("Failed to update IB network in UFM: unknown filed (%s)\n", field)
@@ -65,16 +71,35 @@ var patchCmd = &cobra.Command{
 		}
 		patchCmdOpt.IBNetwork.PKey = pkey
 
-		_, ufmErr := ufmClient.GetIBNetwork(pkey)
+		if err := parseGUIDMembers(&patchCmdOpt.IBNetwork); err != nil {
+			fmt.Printf("Failed to update IB network in UFM: %v\n", err)
+			os.Exit(1)
+		}
+
+		// An empty set removes all the GUIDs of the pkey, e.g. if --guids is missed by mistake.
+		if field == ufm.GUIDField && op == ufm.SetStrategy && len(patchCmdOpt.GUIDMembers()) == 0 && !patchCmdOpt.AllowEmpty {
+			fmt.Printf("Failed to update IB network in UFM: no GUIDs to set, which removes all the GUIDs of pkey 0x%04X; use --allow-empty to confirm\n", pkey)
+			os.Exit(1)
+		}
+
+		_, ufmErr := ufmClient.GetIBNetworkWithContext(ctx, pkey)
 		if ufmErr != nil {
 			fmt.Printf("Failed to get IB network in UFM: %v\n", ufmErr)
 			os.Exit(1)
 		}
 
-		if ufmErr := ufmClient.Patch(&patchCmdOpt.IBNetwork, field, op); ufmErr != nil {
+		res, ufmErr := ufmClient.PatchWithContext(ctx, &patchCmdOpt.IBNetwork, field, op, jobOptions(patchCmdOpt.Wait)...)
+		if ufmErr != nil {
 			fmt.Printf("Failed to update IB network in UFM: %v\n", ufmErr)
 			os.Exit(1)
 		}
+
+		if field == ufm.GUIDField {
+			fmt.Printf("%-15s: %s\n", "Added", strings.Join(res.Added, ","))
+			fmt.Printf("%-15s: %s\n", "Removed", strings.Join(res.Removed, ","))
+			fmt.Printf("%-15s: %s\n", "Updated", strings.Join(res.Updated, ","))
+			fmt.Printf("%-15s: %s\n", "Unchanged", strings.Join(res.Unchanged, ","))
+		}
 	},
 }
 
@@ -82,15 +107,17 @@ func init() {
 	rootCmd.AddCommand(patchCmd)
 
 	patchCmd.Flags().StringVar(&patchCmdOpt.PkeyString, "pkey", "-1", "The pkeys for IB network.")
-	createCmd.MarkFlagRequired("pkey")
+	patchCmd.MarkFlagRequired("pkey")
 	patchCmd.Flags().StringVar(&patchCmdOpt.FieldStr, "field", "guid", "The field of IB network to patch, one of 'qos' or 'guid'.")
 	createCmd.MarkFlagRequired("field")
 	patchCmd.Flags().StringVar(&patchCmdOpt.StrategyStr, "strategy", "add", "The strategy of path, one of 'add', 'delete' or 'set'.")
 	createCmd.MarkFlagRequired("strategy")
-	patchCmd.Flags().StringSliceVar(&patchCmdOpt.GUIDs, "guids", []string{}, "The GUID list of the IB network.")
+	patchCmd.Flags().StringSliceVar(&patchCmdOpt.GUIDs, "guids", []string{}, "The GUID list of the IB network, each GUID may have a membership, e.g. <guid>:limited; default to full.")
 	patchCmd.Flags().Int32Var(&patchCmdOpt.MTU, "mtu", 2048, "The MTU of the services, one of 2k or 4k.")
 	patchCmd.Flags().BoolVar(&patchCmdOpt.IPOverIB, "ip-over-ib", true, "Enable IPoIB.")
 	patchCmd.Flags().BoolVar(&patchCmdOpt.Index0, "index0", false, "Store the PKey at index 0 of the PKey table of the GUID.")
 	patchCmd.Flags().Int32Var(&patchCmdOpt.ServiceLevel, "service-level", 0, "The service level of IB network, value can be range from 0-15")
+	patchCmd.Flags().BoolVar(&patchCmdOpt.AllowEmpty, "allow-empty", false, "Allow the set strategy without GUIDs, which removes all the GUIDs of the IB network.")
+	patchCmd.Flags().BoolVar(&patchCmdOpt.Wait, "wait", true, "Wait for the jobs of UFM to finish; --wait=false returns once the jobs are accepted.")
 	patchCmd.Flags().Float64Var(&patchCmdOpt.RateLimit, "rate-limit", 2.5, "The rate limit of IB network, can be one of the following: 2.5, 10, 30, 5, 20, 40, 60, 80, 120, 14, 56, 112, 168, 25, 100, 200, or 300")
 }
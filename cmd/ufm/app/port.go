@@ -0,0 +1,122 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openbce/kperf/pkg/ufm"
+)
+
+type portCmdOptions struct {
+	GUIDs  []string
+	Port   string
+	Force  bool
+	Wait   bool
+	Output string
+}
+
+var portCmdOpt = portCmdOptions{}
+
+// portCmd represents the port command
+var portCmd = &cobra.Command{
+	Use:   "port",
+	Short: "Enable, disable or reset the ports in UFM",
+	Long: `Enable, disable or reset the ports of the GUIDs in UFM, and wait for the actions to finish
+unless --wait=false; the port of a GUID shared by ports, e.g. a switch, is picked by --port, and
+the switch ports are refused unless --force.`,
+}
+
+// newPortActionCmd creates the sub-command of port for the action.
+func newPortActionCmd(action ufm.PortAction) *cobra.Command {
+	verb := strings.ToUpper(string(action[:1])) + string(action[1:])
+	cmd := &cobra.Command{
+		Use:   string(action),
+		Short: fmt.Sprintf("%s the ports of the GUIDs in UFM", verb),
+		Long:  fmt.Sprintf("%s the ports of the GUIDs in UFM, and wait for the actions to finish unless --wait=false", verb),
+		Run: func(cmd *cobra.Command, args []string) {
+			runPortAction(action)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&portCmdOpt.GUIDs, "guid", []string{}, "The GUIDs of the ports.")
+	cmd.Flags().StringVar(&portCmdOpt.Port, "port", "", "The name of the port, e.g. <system guid>_<port number>, if the GUID is shared by ports; only one GUID is allowed with it.")
+	cmd.Flags().BoolVar(&portCmdOpt.Force, "force", false, "Act on the switch ports.")
+	cmd.Flags().BoolVar(&portCmdOpt.Wait, "wait", true, "Wait for the actions to finish; --wait=false returns once the actions are accepted.")
+	addOutputFlag(cmd, &portCmdOpt.Output)
+	cmd.MarkFlagRequired("guid")
+
+	return cmd
+}
+
+func runPortAction(action ufm.PortAction) {
+	p := newPrinter(portCmdOpt.Output)
+
+	if portCmdOpt.Port != "" && len(portCmdOpt.GUIDs) != 1 {
+		fmt.Printf("Failed to %s port in UFM: --port requires exactly one GUID\n", action)
+		os.Exit(1)
+	}
+
+	ufmClient, err := newUFM()
+	if err != nil {
+		fmt.Printf("Failed to connect to UFM: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := newContext()
+	defer cancel()
+	run := map[ufm.PortAction]func(context.Context, string, string, bool, ...ufm.JobOption) (*ufm.PortActionResult, *ufm.UFMError){
+		ufm.EnablePortAction:  ufmClient.EnablePortWithContext,
+		ufm.DisablePortAction: ufmClient.DisablePortWithContext,
+		ufm.ResetPortAction:   ufmClient.ResetPortWithContext,
+	}[action]
+
+	results := []*ufm.PortActionResult{}
+	var errs []*ufm.UFMError
+	for _, guid := range portCmdOpt.GUIDs {
+		res, ufmErr := run(ctx, guid, portCmdOpt.Port, portCmdOpt.Force, jobOptions(portCmdOpt.Wait)...)
+		if res != nil {
+			results = append(results, res)
+		}
+		if ufmErr != nil {
+			errs = append(errs, ufmErr)
+		}
+	}
+
+	if len(results) != 0 {
+		printObject(p, results)
+	}
+	for _, ufmErr := range errs {
+		fmt.Printf("Failed to %s port in UFM: %v\n", action, ufmErr)
+	}
+	if len(errs) != 0 {
+		os.Exit(1)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(portCmd)
+
+	for _, action := range []ufm.PortAction{ufm.EnablePortAction, ufm.DisablePortAction, ufm.ResetPortAction} {
+		portCmd.AddCommand(newPortActionCmd(action))
+	}
+}
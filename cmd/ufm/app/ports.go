@@ -0,0 +1,86 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openbce/kperf/pkg/ufm"
+)
+
+type portsCmdOptions struct {
+	SystemType string
+	Tier       int32
+	Output     string
+	ufm.PortQuery
+}
+
+var portsCmdOpt = portsCmdOptions{}
+
+// portsCmd represents the ports command
+var portsCmd = &cobra.Command{
+	Use:   "ports",
+	Short: "List the ports in UFM",
+	Long:  `List the ports in UFM, filtered by system, state, speed, tier and GUIDs`,
+	Run: func(cmd *cobra.Command, args []string) {
+		p := newPrinter(portsCmdOpt.Output)
+
+		sysType, err := ufm.ParseSystemType(portsCmdOpt.SystemType)
+		if err != nil {
+			fmt.Printf("Failed to list ports in UFM: %v\n", err)
+			os.Exit(1)
+		}
+		query := portsCmdOpt.PortQuery
+		query.SystemType = sysType
+		if cmd.Flags().Changed("tier") {
+			query.Tier = &portsCmdOpt.Tier
+		}
+
+		ufmClient, err := newUFM()
+		if err != nil {
+			fmt.Printf("Failed to connect to UFM: %v\n", err)
+			os.Exit(1)
+		}
+
+		ctx, cancel := newContext()
+		defer cancel()
+
+		ports, ufmErr := ufmClient.QueryPortsWithContext(ctx, &query)
+		if ufmErr != nil {
+			fmt.Printf("Failed to list ports in UFM: %v\n", ufmErr)
+			os.Exit(1)
+		}
+
+		printObject(p, ports)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(portsCmd)
+
+	portsCmd.Flags().StringVar(&portsCmdOpt.SystemType, "sys-type", "", "The system type of the ports, one of 'Computer', 'Switch' or 'Gateway'; default to all.")
+	portsCmd.Flags().StringVar(&portsCmdOpt.SystemName, "system", "", "The system name of the ports, e.g. the hostname.")
+	portsCmd.Flags().StringVar(&portsCmdOpt.LogicalState, "logical-state", "", "The logical state of the ports, e.g. Active.")
+	portsCmd.Flags().StringVar(&portsCmdOpt.PhysicalState, "physical-state", "", "The physical state of the ports, e.g. LinkUp.")
+	portsCmd.Flags().StringVar(&portsCmdOpt.ActiveSpeed, "speed", "", "The active speed of the ports, e.g. HDR.")
+	portsCmd.Flags().Int32Var(&portsCmdOpt.Tier, "tier", 0, "The tier of the ports.")
+	portsCmd.Flags().StringSliceVar(&portsCmdOpt.GUIDs, "guids", []string{}, "The GUIDs of the ports.")
+	addOutputFlag(portsCmd, &portsCmdOpt.Output)
+}
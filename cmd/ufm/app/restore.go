@@ -0,0 +1,83 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openbce/kperf/pkg/ufm"
+	"github.com/openbce/kperf/pkg/ufm/manifest"
+)
+
+type restoreCmdOptions struct {
+	Filename string
+	Output   string
+	ufm.RestoreOptions
+}
+
+var restoreCmdOpt = restoreCmdOptions{}
+
+// restoreCmd represents the restore command
+var restoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restore the partitions in the snapshot to UFM",
+	Long: `Restore the partitions in the snapshot of 'ufm export' to UFM; the missing partitions are created,
+the drifted ones are updated, and the ones not in the snapshot are deleted with --prune.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		p := newPrinter(restoreCmdOpt.Output)
+
+		snapshot, err := manifest.LoadSnapshotFile(restoreCmdOpt.Filename)
+		if err != nil {
+			fmt.Printf("Failed to load snapshot: %v\n", err)
+			os.Exit(1)
+		}
+
+		ufmClient, err := newUFM()
+		if err != nil {
+			fmt.Printf("Failed to connect to UFM: %v\n", err)
+			os.Exit(1)
+		}
+
+		ctx, cancel := newContext()
+		defer cancel()
+
+		summaries, ufmErr := ufmClient.RestoreWithContext(ctx, snapshot, restoreCmdOpt.RestoreOptions)
+
+		if summaries == nil {
+			summaries = []*ufm.RestoreSummary{}
+		}
+		printObject(p, summaries)
+
+		if ufmErr != nil {
+			fmt.Printf("Failed to restore snapshot: %v\n", ufmErr)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(restoreCmd)
+
+	restoreCmd.Flags().StringVarP(&restoreCmdOpt.Filename, "filename", "f", "", "The snapshot of 'ufm export', '-' means stdin.")
+	restoreCmd.MarkFlagRequired("filename")
+	restoreCmd.Flags().BoolVar(&restoreCmdOpt.Prune, "prune", false, "Delete the partitions which are not in the snapshot, except the default pkey 0x7fff.")
+	restoreCmd.Flags().BoolVar(&restoreCmdOpt.DryRun, "dry-run", false, "Only print the changes without applying them.")
+	addOutputFlag(restoreCmd, &restoreCmdOpt.Output)
+}
@@ -17,17 +17,34 @@ limitations under the License.
 package app
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+
+	"github.com/openbce/kperf/pkg/ufm"
+	"github.com/openbce/kperf/pkg/ufm/ufmconfig"
 )
 
+type rootCmdOptions struct {
+	Timeout  time.Duration
+	AuthType string
+	Context  string
+}
+
+var rootCmdOpt = rootCmdOptions{}
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "ufm",
 	Short: "The command line of UFM",
-	Long: `ufm-client is a command line for UFM administrator to manage the IB network in UFM; the following environment
-values are required to connect to the target UFM:
+	Long: `ufm-client is a command line for UFM administrator to manage the IB network in UFM; the target UFM is either
+the current context in the config file ($UFM_CONFIG or ~/.ufm/config, see 'ufm config'), the one selected by the
+--context flag, or the following environment values, which take precedence over the context:
 
   UFM_USERNAME=<Username of ufm>
   UFM_PASSWORD=<Password of ufm>
@@ -36,6 +53,22 @@ values are required to connect to the target UFM:
   UFM_HTTP_SCHEMA=<http or https>
   UFM_CERTIFICATE=<Certificate of ufm>
 
+the UFM_TOKEN=<Access token of ufm> is required instead of the username and password for token auth,
+which is selected by UFM_AUTH_TYPE=<basic, token or session> or the --auth-type flag.
+
+the following environment values are optional:
+
+  UFM_CERTIFICATE_FILE=<Path of the CA bundle of ufm>
+  UFM_CLIENT_CERT_FILE=<Path of the client certificate for mTLS>
+  UFM_CLIENT_KEY_FILE=<Path of the client key for mTLS>
+  UFM_SERVER_NAME=<Server name to verify the certificate of ufm>
+  UFM_TLS_MIN_VERSION=<Minimum TLS version, 1.2 or 1.3>
+  UFM_INSECURE_SKIP_VERIFY=<Skip the verification of ufm certificate>
+  UFM_RETRY_MAX_ATTEMPTS=<Max attempts of a request to ufm, 1 means no retry>
+  UFM_RETRY_BACKOFF=<Initial backoff between the retries, e.g. 500ms>
+  UFM_PKEY_RANGE=<Range to allocate the pkeys from, e.g. 0x100-0x1ff>
+  UFM_PKEY_RESERVED=<Reserved pkey ranges never allocated, e.g. 0x1-0xff,0x7f00-0x7ffe>
+
 `,
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
@@ -49,7 +82,74 @@ func Execute() {
 	}
 }
 
+// newUFM connects to the UFM according to the context in the config file, the
+// environment values and the global flags, in order of increasing precedence.
+func newUFM() (*ufm.UFM, error) {
+	return newUFMForContext(rootCmdOpt.Context, true)
+}
+
+// newUFMForContext connects to the UFM of the context in the config file; the environment
+// values are ignored unless withEnv, e.g. to connect to a second UFM.
+func newUFMForContext(name string, withEnv bool) (*ufm.UFM, error) {
+	path, err := ufmconfig.DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	file, err := ufmconfig.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	conf, err := file.UFMConfig(name)
+	if err != nil {
+		return nil, err
+	}
+	if withEnv {
+		if err := conf.OverrideFromEnv(); err != nil {
+			return nil, err
+		}
+	}
+	if rootCmdOpt.AuthType != "" {
+		conf.AuthType = rootCmdOpt.AuthType
+	}
+
+	return ufm.NewUFMWithConfig(conf, ufm.WithUserAgent("ufm-cli"))
+}
+
+// newContext returns the context for the requests to UFM; it's cancelled
+// when the timeout is reached or the command is interrupted.
+func newContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	if rootCmdOpt.Timeout <= 0 {
+		return ctx, stop
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, rootCmdOpt.Timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+// jobOptions makes the mutating requests wait for their jobs in UFM unless !wait, and reports
+// the jobs to stderr to keep the output parsable.
+func jobOptions(wait bool) []ufm.JobOption {
+	return []ufm.JobOption{
+		ufm.WithWait(wait),
+		ufm.WithJobProgress(func(job *ufm.Job) {
+			if job.Status == ufm.JobAccepted || job.IsDone() {
+				fmt.Fprintf(os.Stderr, "Job %s %s.\n", job.ID, strings.ToLower(string(job.Status)))
+				return
+			}
+			fmt.Fprintf(os.Stderr, "Job %s %s: %d%%\n", job.ID, strings.ToLower(string(job.Status)), job.Progress)
+		}),
+	}
+}
+
 func init() {
+	rootCmd.PersistentFlags().StringVar(&rootCmdOpt.Context, "context", "", "The name of the context in the config file to use; default to the current context.")
+	rootCmd.PersistentFlags().StringVar(&rootCmdOpt.AuthType, "auth-type", "", "The authentication type of UFM, one of 'basic', 'token' or 'session'; overrides UFM_AUTH_TYPE.")
+	rootCmd.PersistentFlags().DurationVar(&rootCmdOpt.Timeout, "timeout", 0, "The timeout of the requests to UFM, e.g. 30s; 0 means no timeout.")
+
 	// TODO(k82cn): add a flag on log level
 	// zerolog.SetGlobalLevel(zerolog.InfoLevel)
 }
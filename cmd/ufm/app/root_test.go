@@ -0,0 +1,135 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openbce/kperf/pkg/ufm"
+	"github.com/openbce/kperf/pkg/ufm/ufmconfig"
+	"github.com/openbce/kperf/pkg/ufm/ufmtest"
+)
+
+// setTimeout sets the global --timeout flag during the test.
+func setTimeout(t *testing.T, timeout time.Duration) {
+	old := rootCmdOpt.Timeout
+	rootCmdOpt.Timeout = timeout
+	t.Cleanup(func() { rootCmdOpt.Timeout = old })
+}
+
+func TestNewContext(t *testing.T) {
+	setTimeout(t, 0)
+	ctx, cancel := newContext()
+	if _, found := ctx.Deadline(); found {
+		t.Errorf("unexpected deadline without --timeout")
+	}
+	cancel()
+	if ctx.Err() == nil {
+		t.Errorf("the context is not cancelled")
+	}
+
+	setTimeout(t, time.Minute)
+	ctx, cancel = newContext()
+	defer cancel()
+	deadline, found := ctx.Deadline()
+	if !found {
+		t.Fatalf("no deadline with --timeout")
+	}
+	if d := time.Until(deadline); d <= 0 || d > time.Minute {
+		t.Errorf("deadline is %v later, expected within %v", d, time.Minute)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	srv := ufmtest.NewServer()
+	t.Cleanup(srv.Close)
+	srv.AddFault(ufmtest.Fault{Path: "/ufmRest/app/ufm_version", Latency: time.Second})
+
+	t.Setenv(ufmconfig.EnvConfigPath, filepath.Join(t.TempDir(), "config"))
+	for _, env := range srv.Env() {
+		kv := strings.SplitN(env, "=", 2)
+		t.Setenv(kv[0], kv[1])
+	}
+	t.Setenv("UFM_RETRY_MAX_ATTEMPTS", "1")
+
+	u, err := newUFM()
+	if err != nil {
+		t.Fatalf("failed to create UFM: %v", err)
+	}
+
+	setTimeout(t, 50*time.Millisecond)
+	ctx, cancel := newContext()
+	defer cancel()
+
+	start := time.Now()
+	_, ufmErr := u.VersionWithContext(ctx)
+	if ufmErr == nil {
+		t.Fatalf("expected error of timeout")
+	}
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Errorf("the request took %v, the timeout is not propagated", elapsed)
+	}
+	if ufmErr.Code != ufm.TimeoutErr || !errors.Is(ufmErr, context.DeadlineExceeded) {
+		t.Errorf("unexpected error %v", ufmErr)
+	}
+}
+
+func TestContextWithEnv(t *testing.T) {
+	srv := ufmtest.NewServer()
+	t.Cleanup(srv.Close)
+	conf := srv.Config()
+
+	path := filepath.Join(t.TempDir(), "config")
+	t.Setenv(ufmconfig.EnvConfigPath, path)
+	file := &ufmconfig.Config{CurrentContext: "staging"}
+	file.SetContext("staging", ufmconfig.Context{Address: "ufm-staging.invalid", Schema: "http", Credential: "admin"})
+	file.SetContext("fake", ufmconfig.Context{Address: conf.Address, Port: conf.Port, Schema: "http", Credential: "admin"})
+	file.SetCredential("admin", ufmconfig.Credential{Username: conf.Username, Password: conf.Password})
+	if err := file.Save(path); err != nil {
+		t.Fatalf("failed to save config: %v", err)
+	}
+
+	old := rootCmdOpt.Context
+	t.Cleanup(func() { rootCmdOpt.Context = old })
+
+	// --context selects the context instead of the current one.
+	rootCmdOpt.Context = "fake"
+	u, err := newUFM()
+	if err != nil {
+		t.Fatalf("failed to create UFM: %v", err)
+	}
+	if _, ufmErr := u.Version(); ufmErr != nil {
+		t.Errorf("failed to connect to the UFM of context fake: %v", ufmErr)
+	}
+
+	// The environment values take precedence over the current context.
+	rootCmdOpt.Context = ""
+	t.Setenv("UFM_ADDRESS", conf.Address)
+	t.Setenv("UFM_PORT", strconv.Itoa(conf.Port))
+	if u, err = newUFM(); err != nil {
+		t.Fatalf("failed to create UFM: %v", err)
+	}
+	if _, ufmErr := u.Version(); ufmErr != nil {
+		t.Errorf("failed to connect to the UFM of the environment values: %v", ufmErr)
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openbce/kperf/pkg/ufm"
+)
+
+type statsCmdOptions struct {
+	PkeyStr  string
+	Interval time.Duration
+	Count    int
+	Output   string
+}
+
+var statsCmdOpt = statsCmdOptions{}
+
+// statsCmd represents the stats command
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show the live counters of the ports in a IB network",
+	Long: `Show the live counters of the ports in a IB network by a monitoring session of UFM; the rates are
+computed between the samples of every interval, and the session is deleted when the command exits.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		p := newPrinter(statsCmdOpt.Output)
+
+		pkey, err := ufm.ParsePkey(statsCmdOpt.PkeyStr)
+		if err != nil {
+			fmt.Printf("Failed to get stats of IB network in UFM: %v\n", err)
+			os.Exit(1)
+		}
+
+		ufmClient, err := newUFM()
+		if err != nil {
+			fmt.Printf("Failed to connect to UFM: %v\n", err)
+			os.Exit(1)
+		}
+
+		ctx, cancel := newContext()
+		defer cancel()
+
+		ib, ufmErr := ufmClient.GetIBNetworkWithContext(ctx, pkey)
+		if ufmErr != nil {
+			fmt.Printf("Failed to get IB network in UFM: %v\n", ufmErr)
+			os.Exit(1)
+		}
+
+		guids := ib.GUIDs
+		if ib.PKey == ufm.DefaultPKey {
+			guids = nil
+		}
+		ibPorts, ufmErr := ufmClient.ListPortWithContext(ctx, guids...)
+		if ufmErr != nil {
+			fmt.Printf("Failed to get ports of IB network in UFM: %v\n", ufmErr)
+			os.Exit(1)
+		}
+		if len(ibPorts) == 0 {
+			fmt.Printf("Failed to get stats of IB network in UFM: no ports in pkey 0x%04x\n", pkey)
+			os.Exit(1)
+		}
+
+		var names []string
+		for _, port := range ibPorts {
+			names = append(names, port.Name)
+		}
+		session, ufmErr := ufmClient.CreateMonitoringSessionWithContext(ctx, names, statsCmdOpt.Interval)
+		if ufmErr != nil {
+			fmt.Printf("Failed to create monitoring session in UFM: %v\n", ufmErr)
+			os.Exit(1)
+		}
+
+		// The session is deleted even if the command is interrupted.
+		deleteSession := func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			if ufmErr := ufmClient.DeleteMonitoringSessionWithContext(ctx, session.ID); ufmErr != nil {
+				fmt.Printf("Failed to delete monitoring session %s in UFM: %v\n", session.ID, ufmErr)
+			}
+		}
+		defer deleteSession()
+
+		var prev *ufm.PortSample
+		for printed := 0; ; {
+			cur, ufmErr := ufmClient.SampleMonitoringSessionWithContext(ctx, session.ID)
+			if ufmErr != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				fmt.Printf("Failed to sample monitoring session %s in UFM: %v\n", session.ID, ufmErr)
+				deleteSession()
+				os.Exit(1)
+			}
+
+			// The rates are unknown until the second sample.
+			if prev != nil {
+				printObject(p, ufm.ComputePortStats(prev, cur))
+				printed++
+			}
+			prev = cur
+
+			if statsCmdOpt.Count > 0 && printed >= statsCmdOpt.Count {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(session.Interval):
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+
+	statsCmd.Flags().StringVar(&statsCmdOpt.PkeyStr, "pkey", "", "The pkey of the IB network.")
+	statsCmd.Flags().DurationVar(&statsCmdOpt.Interval, "interval", 5*time.Second, "The interval to sample the counters, rounded up to seconds.")
+	statsCmd.Flags().IntVar(&statsCmdOpt.Count, "count", 0, "The number of the samples to show; 0 means until interrupted.")
+	addOutputFlag(statsCmd, &statsCmdOpt.Output)
+	statsCmd.MarkFlagRequired("pkey")
+}
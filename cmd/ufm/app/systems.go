@@ -0,0 +1,74 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+type systemsCmdOptions struct {
+	Output string
+}
+
+var systemsCmdOpt = systemsCmdOptions{}
+
+// systemsCmd represents the systems command
+var systemsCmd = &cobra.Command{
+	Use:   "systems [NAME]",
+	Short: "List the systems in UFM",
+	Long:  `List the hosts, switches and gateways in UFM, or get the system of the NAME, i.e. the system ID of its ports`,
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		p := newPrinter(systemsCmdOpt.Output)
+
+		ufmClient, err := newUFM()
+		if err != nil {
+			fmt.Printf("Failed to connect to UFM: %v\n", err)
+			os.Exit(1)
+		}
+
+		ctx, cancel := newContext()
+		defer cancel()
+
+		if len(args) != 0 {
+			system, ufmErr := ufmClient.GetSystemWithContext(ctx, args[0])
+			if ufmErr != nil {
+				fmt.Printf("Failed to get system in UFM: %v\n", ufmErr)
+				os.Exit(1)
+			}
+			printObject(p, system)
+			return
+		}
+
+		systems, ufmErr := ufmClient.ListSystemsWithContext(ctx)
+		if ufmErr != nil {
+			fmt.Printf("Failed to list systems in UFM: %v\n", ufmErr)
+			os.Exit(1)
+		}
+
+		printObject(p, systems)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(systemsCmd)
+
+	addOutputFlag(systemsCmd, &systemsCmdOpt.Output)
+}
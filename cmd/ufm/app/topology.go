@@ -0,0 +1,93 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openbce/kperf/pkg/ufm"
+	"github.com/openbce/kperf/pkg/ufm/printer"
+)
+
+type topologyCmdOptions struct {
+	PkeyStr string
+	Output  string
+}
+
+var topologyCmdOpt = topologyCmdOptions{}
+
+// topologyCmd represents the topology command
+var topologyCmd = &cobra.Command{
+	Use:   "topology",
+	Short: "Export the topology of the fabric in UFM",
+	Long: `Export the topology of the fabric in UFM, e.g. 'ufm topology -o dot | dot -Tsvg > fabric.svg';
+the nodes are the systems and the edges are the links between their ports. With --pkey, only the
+links of the GUIDs in the pkey are exported, e.g. to find the leaf switches of its hosts.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		p := newPrinter(topologyCmdOpt.Output)
+
+		ufmClient, err := newUFM()
+		if err != nil {
+			fmt.Printf("Failed to connect to UFM: %v\n", err)
+			os.Exit(1)
+		}
+
+		ctx, cancel := newContext()
+		defer cancel()
+
+		var guids []string
+		if topologyCmdOpt.PkeyStr != "" {
+			pkey, err := ufm.ParsePkey(topologyCmdOpt.PkeyStr)
+			if err != nil {
+				fmt.Printf("Failed to get topology in UFM: %v\n", err)
+				os.Exit(1)
+			}
+			ib, ufmErr := ufmClient.GetIBNetworkWithContext(ctx, pkey)
+			if ufmErr != nil {
+				fmt.Printf("Failed to get IB network in UFM: %v\n", ufmErr)
+				os.Exit(1)
+			}
+			if len(ib.GUIDs) == 0 {
+				fmt.Printf("Failed to get topology in UFM: no GUIDs in pkey 0x%04x\n", pkey)
+				os.Exit(1)
+			}
+			guids = ib.GUIDs
+		}
+
+		topology, ufmErr := ufmClient.GetTopologyWithContext(ctx, guids...)
+		if ufmErr != nil {
+			fmt.Printf("Failed to get topology in UFM: %v\n", ufmErr)
+			os.Exit(1)
+		}
+
+		if printer.IsTable(topologyCmdOpt.Output) {
+			printObject(p, topology.Edges)
+			return
+		}
+		printObject(p, topology)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(topologyCmd)
+
+	topologyCmd.Flags().StringVar(&topologyCmdOpt.PkeyStr, "pkey", "", "The pkey of the IB network to limit the topology to its GUIDs.")
+	topologyCmd.Flags().StringVarP(&topologyCmdOpt.Output, "output", "o", "", fmt.Sprintf("Output format, one of %s.", printer.GraphFormats))
+}
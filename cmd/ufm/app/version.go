@@ -21,8 +21,6 @@ import (
 	"os"
 
 	"github.com/spf13/cobra"
-
-	"github.com/openbce/kperf/pkg/ufm"
 )
 
 // versionCmd represents the list command
@@ -31,12 +29,15 @@ var versionCmd = &cobra.Command{
 	Short: "Show the release version of UFM",
 	Long:  `Show the release version of UFM`,
 	Run: func(cmd *cobra.Command, args []string) {
-		ufmClient, err := ufm.NewUFM()
+		ufmClient, err := newUFM()
 		if err != nil {
 			fmt.Printf("Failed to connect to UFM: %v\n", err)
 			os.Exit(1)
 		}
-		ver, ufmErr := ufmClient.Version()
+
+		ctx, cancel := newContext()
+		defer cancel()
+		ver, ufmErr := ufmClient.VersionWithContext(ctx)
 		if ufmErr != nil {
 			fmt.Printf("Failed to get version of UFM: %v\n", ufmErr)
 			os.Exit(1)
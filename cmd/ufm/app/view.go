@@ -19,18 +19,35 @@ package app
 import (
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/openbce/kperf/pkg/ufm"
+	"github.com/openbce/kperf/pkg/ufm/printer"
 )
 
 type viewCmdOptions struct {
 	PkeyStr string
+	Output  string
 	ufm.IBNetwork
 }
 
+// ibNetworkDetail is the IB network with its ports and their systems in the output of view.
+type ibNetworkDetail struct {
+	*ufm.IBNetwork
+	Ports   []*ufm.IBPort   `json:"ports"`
+	Systems []*ufm.IBSystem `json:"systems"`
+}
+
+// systemPorts is the ports of a system; the system is nil if it's not found in UFM.
+type systemPorts struct {
+	id     string
+	system *ufm.IBSystem
+	ports  []*ufm.IBPort
+}
+
 var viewCmdOpt = viewCmdOptions{}
 
 // viewCmd represents the list command
@@ -39,19 +56,24 @@ var viewCmd = &cobra.Command{
 	Short: "View the detail of a IB network in UFM",
 	Long:  `View the detail of a IB network in UFM`,
 	Run: func(cmd *cobra.Command, args []string) {
-		ufmClient, err := ufm.NewUFM()
+		p := newPrinter(viewCmdOpt.Output)
+
+		ufmClient, err := newUFM()
 		if err != nil {
 			fmt.Printf("Failed to connect to UFM: %v\n", err)
 			os.Exit(1)
 		}
 
+		ctx, cancel := newContext()
+		defer cancel()
+
 		pkey, err := ufm.ParsePkey(viewCmdOpt.PkeyStr)
 		if err != nil {
 			fmt.Printf("Failed to get IB network in UFM: %v\n", err)
 			os.Exit(1)
 		}
 
-		ib, ufmErr := ufmClient.GetIBNetwork(pkey)
+		ib, ufmErr := ufmClient.GetIBNetworkWithContext(ctx, pkey)
 		if ufmErr != nil {
 			fmt.Printf("Failed to get IB network in UFM: %v\n", ufmErr)
 			os.Exit(1)
@@ -62,12 +84,30 @@ var viewCmd = &cobra.Command{
 			guids = nil
 		}
 
-		ibPorts, ufmErr := ufmClient.ListPort(guids...)
+		ibPorts, ufmErr := ufmClient.ListPortWithContext(ctx, guids...)
 		if ufmErr != nil {
 			fmt.Printf("Failed to get ports of IB network in UFM: %v\n", ufmErr)
 			os.Exit(1)
 		}
 
+		systems, ufmErr := ufmClient.ListSystemsWithContext(ctx)
+		if ufmErr != nil {
+			fmt.Printf("Failed to get systems of IB network in UFM: %v\n", ufmErr)
+			os.Exit(1)
+		}
+		groups := groupPortsBySystem(ibPorts, systems)
+
+		if !printer.IsTable(viewCmdOpt.Output) {
+			detail := &ibNetworkDetail{IBNetwork: ib, Ports: ibPorts, Systems: []*ufm.IBSystem{}}
+			for _, g := range groups {
+				if g.system != nil {
+					detail.Systems = append(detail.Systems, g.system)
+				}
+			}
+			printObject(p, detail)
+			return
+		}
+
 		fmt.Printf("%-15s: %s\n", "Name", ib.Name)
 		fmt.Printf("%-15s: 0x%x\n", "Pkey", ib.PKey)
 		fmt.Printf("%-15s: %t\n", "IPoIB", ib.IPOverIB)
@@ -75,15 +115,12 @@ var viewCmd = &cobra.Command{
 		fmt.Printf("%-15s: %d\n", "MTU", ib.MTU)
 		fmt.Printf("%-15s: %.2f\n", "Rate Limit", ib.RateLimit)
 		fmt.Printf("%-15s: %d\n", "Service Level", ib.ServiceLevel)
-		fmt.Printf("%-15s: %s\n", "GUIDs", strings.Join(ib.GUIDs, ","))
+		fmt.Printf("%-15s: %s\n", "GUIDs", strings.Join(formatGUIDMembers(ib), ","))
 		fmt.Printf("%-15s:\n", "Ports")
-		if len(ibPorts) != 0 {
-			fmt.Printf("    %-20s%-20s%-20s%-15s%-15s%-10s%-20s%-20s\n", "Name", "GUID", "SystemID", "SystemName", "DName", "LID", "LogicalState", "PhysicalState")
-			for _, p := range ibPorts {
-				fmt.Printf("    %-20s%-20s%-20s%-15s%-15s%-10d%-20s%-20s\n", p.Name, p.GUID, p.SystemID, p.SystemName, p.DName, p.LID, p.LogicalState, p.PhysicalState)
-			}
+		for _, g := range groups {
+			fmt.Printf("\n%s\n", describeSystem(g))
+			printObject(p, g.ports)
 		}
-
 	},
 }
 
@@ -91,5 +128,72 @@ func init() {
 	rootCmd.AddCommand(viewCmd)
 
 	viewCmd.Flags().StringVar(&viewCmdOpt.PkeyStr, "pkey", "", "The pkeys for IB network.")
-	createCmd.MarkFlagRequired("pkey")
+	addOutputFlag(viewCmd, &viewCmdOpt.Output)
+	viewCmd.MarkFlagRequired("pkey")
+}
+
+// formatGUIDMembers formats the GUIDs as the --guids flag, e.g. <guid>:limited for the limited members.
+func formatGUIDMembers(ib *ufm.IBNetwork) []string {
+	var res []string
+	for _, m := range ib.GUIDMembers() {
+		res = append(res, m.String())
+	}
+	return res
+}
+
+// groupPortsBySystem groups the ports by their system ID, in the order of the system names.
+func groupPortsBySystem(ports []*ufm.IBPort, systems []*ufm.IBSystem) []*systemPorts {
+	systemByID := map[string]*ufm.IBSystem{}
+	for _, s := range systems {
+		systemByID[s.Name] = s
+	}
+
+	groupByID := map[string]*systemPorts{}
+	var res []*systemPorts
+	for _, p := range ports {
+		g, found := groupByID[p.SystemID]
+		if !found {
+			g = &systemPorts{id: p.SystemID, system: systemByID[p.SystemID]}
+			groupByID[p.SystemID] = g
+			res = append(res, g)
+		}
+		g.ports = append(g.ports, p)
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return systemName(res[i]) < systemName(res[j])
+	})
+
+	return res
+}
+
+func systemName(g *systemPorts) string {
+	if g.system != nil && g.system.SystemName != "" {
+		return g.system.SystemName
+	}
+	if len(g.ports) != 0 && g.ports[0].SystemName != "" {
+		return g.ports[0].SystemName
+	}
+	return g.id
+}
+
+// describeSystem describes the system of the ports, e.g. "System: host1 (ID: sys1, Model: ConnectX-7, Role: endpoint)".
+func describeSystem(g *systemPorts) string {
+	attrs := []string{"ID: " + g.id}
+	if g.system != nil {
+		for _, a := range []struct{ name, value string }{
+			{"Type", g.system.Type},
+			{"Model", g.system.Model},
+			{"Firmware", g.system.FirmwareVersion},
+			{"Role", g.system.Role},
+			{"IP", g.system.IP},
+			{"State", g.system.State},
+		} {
+			if a.value != "" {
+				attrs = append(attrs, a.name+": "+a.value)
+			}
+		}
+	}
+
+	return fmt.Sprintf("System: %s (%s)", systemName(g), strings.Join(attrs, ", "))
 }
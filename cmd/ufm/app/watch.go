@@ -0,0 +1,130 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openbce/kperf/pkg/ufm"
+)
+
+type watchCmdOptions struct {
+	Interval     time.Duration
+	ResyncPeriod time.Duration
+	PkeyStr      string
+	Output       string
+}
+
+var watchCmdOpt = watchCmdOptions{}
+
+// watchCmd represents the watch command
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Watch the changes of the partitions in UFM",
+	Long: `Watch the changes of the partitions and their GUIDs in UFM by polling; the existing partitions
+are reported as ADDED at first, and the events are streamed until interrupted.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		p := newPrinter(watchCmdOpt.Output)
+
+		if watchCmdOpt.Interval <= 0 {
+			fmt.Printf("Failed to watch partitions: invalid interval %v, it must be positive\n", watchCmdOpt.Interval)
+			os.Exit(1)
+		}
+
+		var pkey int32 = -1
+		if watchCmdOpt.PkeyStr != "" {
+			var err error
+			if pkey, err = ufm.ParsePkey(watchCmdOpt.PkeyStr); err != nil {
+				fmt.Printf("Failed to watch partitions: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		ufmClient, err := newUFM()
+		if err != nil {
+			fmt.Printf("Failed to connect to UFM: %v\n", err)
+			os.Exit(1)
+		}
+
+		ctx, cancel := newContext()
+		defer cancel()
+
+		watcher, ufmErr := ufmClient.NewWatcher(watchCmdOpt.Interval)
+		if ufmErr != nil {
+			fmt.Printf("Failed to watch partitions: %v\n", ufmErr)
+			os.Exit(1)
+		}
+		watcher.ResyncPeriod = watchCmdOpt.ResyncPeriod
+		watcher.OnError = func(ufmErr *ufm.UFMError) {
+			fmt.Fprintf(os.Stderr, "Failed to list partitions: %v\n", ufmErr)
+		}
+		events := watcher.Events(100)
+		go watcher.Run(ctx)
+
+		for first := true; ; first = false {
+			batch, open := nextEvents(events, pkey)
+			if len(batch) != 0 || first {
+				printStream(p, watchCmdOpt.Output, batch, !first)
+			}
+			if !open {
+				return
+			}
+		}
+	},
+}
+
+// nextEvents waits for the next events of the pkey, or of all the pkeys if it's negative, and
+// returns them with the ones already received, e.g. the events of a poll, so they are printed
+// together; it returns false if the channel is closed.
+func nextEvents(events <-chan ufm.Event, pkey int32) ([]*ufm.Event, bool) {
+	res := []*ufm.Event{}
+	add := func(e ufm.Event) {
+		if pkey < 0 || e.PKey == pkey {
+			res = append(res, &e)
+		}
+	}
+
+	e, open := <-events
+	if !open {
+		return res, false
+	}
+	add(e)
+	for {
+		select {
+		case e, open := <-events:
+			if !open {
+				return res, false
+			}
+			add(e)
+		default:
+			return res, true
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	watchCmd.Flags().DurationVar(&watchCmdOpt.Interval, "interval", 10*time.Second, "The interval to poll the partitions, which must be positive.")
+	watchCmd.Flags().DurationVar(&watchCmdOpt.ResyncPeriod, "resync", 0, "The period to report all the partitions as SYNC; 0 means no resync.")
+	watchCmd.Flags().StringVar(&watchCmdOpt.PkeyStr, "pkey", "", "Only watch the partition of the pkey.")
+	addOutputFlag(watchCmd, &watchCmdOpt.Output)
+}
@@ -6,6 +6,7 @@ require (
 	github.com/caarlos0/env/v6 v6.10.1
 	github.com/rs/zerolog v1.29.0
 	github.com/spf13/cobra v1.6.1
+	sigs.k8s.io/yaml v1.4.0
 )
 
 require (
@@ -0,0 +1,192 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	actionsPath = "/ufmRest/actions"
+)
+
+// PortAction is an administrative action on a port.
+type PortAction string
+
+const (
+	EnablePortAction  PortAction = "enable"
+	DisablePortAction PortAction = "disable"
+	ResetPortAction   PortAction = "reset"
+)
+
+// PortActionResult reports an action on a port and the final status of its job in UFM.
+type PortActionResult struct {
+	GUID string `json:"guid"`
+	// The name of the port, i.e. IBPort.Name.
+	Port   string     `json:"port"`
+	System string     `json:"system"`
+	Action PortAction `json:"action"`
+	// The ID of the job of the action in UFM, which is empty if the action finished synchronously.
+	JobID   string    `json:"job_id,omitempty"`
+	Status  JobStatus `json:"status"`
+	Summary string    `json:"summary,omitempty"`
+}
+
+func (u *UFM) EnablePort(guid, port string, force bool, opts ...JobOption) (*PortActionResult, *UFMError) {
+	return u.EnablePortWithContext(context.Background(), guid, port, force, opts...)
+}
+
+// EnablePortWithContext enables the port of the GUID, which is picked by its name if the GUID is
+// shared by ports, e.g. the ports of a switch; the switch ports are refused unless force.
+func (u *UFM) EnablePortWithContext(ctx context.Context, guid, port string, force bool, opts ...JobOption) (*PortActionResult, *UFMError) {
+	return u.runPortAction(ctx, EnablePortAction, guid, port, force, newJobOptions(opts...))
+}
+
+func (u *UFM) DisablePort(guid, port string, force bool, opts ...JobOption) (*PortActionResult, *UFMError) {
+	return u.DisablePortWithContext(context.Background(), guid, port, force, opts...)
+}
+
+// DisablePortWithContext disables the port of the GUID, which is picked by its name if the GUID is
+// shared by ports, e.g. the ports of a switch; the switch ports are refused unless force.
+func (u *UFM) DisablePortWithContext(ctx context.Context, guid, port string, force bool, opts ...JobOption) (*PortActionResult, *UFMError) {
+	return u.runPortAction(ctx, DisablePortAction, guid, port, force, newJobOptions(opts...))
+}
+
+func (u *UFM) ResetPort(guid, port string, force bool, opts ...JobOption) (*PortActionResult, *UFMError) {
+	return u.ResetPortWithContext(context.Background(), guid, port, force, opts...)
+}
+
+// ResetPortWithContext resets the port of the GUID, which is picked by its name if the GUID is
+// shared by ports, e.g. the ports of a switch; the switch ports are refused unless force.
+func (u *UFM) ResetPortWithContext(ctx context.Context, guid, port string, force bool, opts ...JobOption) (*PortActionResult, *UFMError) {
+	return u.runPortAction(ctx, ResetPortAction, guid, port, force, newJobOptions(opts...))
+}
+
+// runPortAction starts the action on the port of the GUID, and waits for its job to finish
+// unless WithWait(false); the result is returned with the error if the job failed.
+func (u *UFM) runPortAction(ctx context.Context, action PortAction, guid, portName string, force bool, jo *jobOptions) (*PortActionResult, *UFMError) {
+	port, ufmErr := u.findActionPort(ctx, guid, portName, force)
+	if ufmErr != nil {
+		return nil, ufmErr
+	}
+
+	req := struct {
+		Params struct {
+			PortID string `json:"port_id"`
+		} `json:"params"`
+		Action      PortAction `json:"action"`
+		ObjectIDs   []string   `json:"object_ids"`
+		ObjectType  string     `json:"object_type"`
+		Description string     `json:"description"`
+		Identifier  string     `json:"identifier"`
+	}{
+		Action:      action,
+		ObjectIDs:   []string{port.SystemID},
+		ObjectType:  "System",
+		Description: fmt.Sprintf("%s port %s", action, port.Name),
+		Identifier:  "id",
+	}
+	req.Params.PortID = port.Name
+
+	body, err := json.Marshal(&req)
+	if err != nil {
+		return nil, &UFMError{
+			Code:    UnknownErr,
+			Message: fmt.Sprintf("failed to marshal action with error: %v", err),
+			Err:     err,
+		}
+	}
+
+	resp, ufmErr := u.client.DoWithContext(ctx, http.MethodPost, u.buildURL(actionsPath), body)
+	if ufmErr != nil {
+		return nil, wrapError(ufmErr, "failed to %s port %s", action, port.Name)
+	}
+
+	res := &PortActionResult{GUID: port.GUID, Port: port.Name, System: port.SystemName, Action: action, Status: JobCompleted}
+	job, ufmErr := u.trackJob(ctx, resp, jo)
+	if job != nil {
+		res.JobID, res.Status, res.Summary = job.ID, job.Status, job.Summary
+	}
+	if ufmErr != nil {
+		return res, wrapError(ufmErr, "failed to %s port %s", action, port.Name)
+	}
+
+	return res, nil
+}
+
+// findActionPort returns the port of the GUID by its name, or the only port of the GUID if the
+// name is empty, as the GUID of a switch is shared by all its ports; it's an error if the port
+// is a switch port without force.
+func (u *UFM) findActionPort(ctx context.Context, guid, name string, force bool) (*IBPort, *UFMError) {
+	ports, ufmErr := u.QueryPortsWithContext(ctx, &PortQuery{GUIDs: []string{guid}})
+	if ufmErr != nil {
+		return nil, ufmErr
+	}
+	if len(ports) == 0 {
+		return nil, &UFMError{
+			Code:    NotFoundErr,
+			Message: fmt.Sprintf("port of GUID %s not found", guid),
+		}
+	}
+
+	var port *IBPort
+	if name != "" {
+		for _, p := range ports {
+			if p.Name == name {
+				port = p
+			}
+		}
+		if port == nil {
+			return nil, &UFMError{
+				Code:    NotFoundErr,
+				Message: fmt.Sprintf("port %s of GUID %s not found", name, guid),
+			}
+		}
+	} else if len(ports) > 1 {
+		var names []string
+		for _, p := range ports {
+			names = append(names, p.Name)
+		}
+		return nil, &UFMError{
+			Code:    BadRequestErr,
+			Message: fmt.Sprintf("GUID %s is shared by ports %s, the name of the port is required", guid, strings.Join(names, ",")),
+		}
+	} else {
+		port = ports[0]
+	}
+
+	if !force {
+		switches, ufmErr := u.QueryPortsWithContext(ctx, &PortQuery{SystemType: SwitchSystem, GUIDs: []string{guid}})
+		if ufmErr != nil {
+			return nil, ufmErr
+		}
+		for _, p := range switches {
+			if p.Name == port.Name {
+				return nil, &UFMError{
+					Code:    BadRequestErr,
+					Message: fmt.Sprintf("port %s of GUID %s is a switch port, force is required", port.Name, guid),
+				}
+			}
+		}
+	}
+
+	return port, nil
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufm_test
+
+import (
+	"testing"
+
+	"github.com/openbce/kperf/pkg/ufm"
+)
+
+func TestPortActions(t *testing.T) {
+	u, srv := newUFM(t, "")
+	srv.AddPort(ufm.IBPort{Name: "0002c90300a1b2c1_1", GUID: guid1, SystemID: "0002c90300a1b2c1", LogicalState: "Active"}, "Computer")
+	srv.AddPort(ufm.IBPort{Name: "0002c90300a1b2c3_1", GUID: guid3, SystemID: "0002c90300a1b2c3", LogicalState: "Active"}, "Switch")
+
+	// The action is accepted with {"id": <job id>}, and waited until the job completes.
+	res, err := u.DisablePort(guid1, "", false)
+	if err != nil {
+		t.Fatalf("failed to disable port: %v", err)
+	}
+	if res.JobID == "" || res.Status != ufm.JobCompleted {
+		t.Errorf("unexpected result %+v", res)
+	}
+	if p, _ := srv.Port("0002c90300a1b2c1_1"); p.LogicalState != "Down" {
+		t.Errorf("port is %s after disable", p.LogicalState)
+	}
+
+	if _, err := u.ResetPort(guid3, "", false); err == nil {
+		t.Errorf("expected error to reset a switch port without force")
+	}
+	if _, err := u.ResetPort(guid3, "", true); err != nil {
+		t.Errorf("failed to reset a switch port with force: %v", err)
+	}
+
+	srv.FailJobs("port is busy")
+	res, err = u.EnablePort(guid1, "", false)
+	if err == nil {
+		t.Fatalf("expected error of the failed job")
+	}
+	if res == nil || res.Status != ufm.JobCompletedWithErrors || res.Summary != "port is busy" {
+		t.Errorf("unexpected result of the failed job %+v", res)
+	}
+}
+
+func TestSwitchPortActions(t *testing.T) {
+	u, srv := newUFM(t, "")
+	// All the ports of a switch share the GUID of the switch.
+	for _, name := range []string{"0002c90300a1b2c3_1", "0002c90300a1b2c3_2"} {
+		srv.AddPort(ufm.IBPort{Name: name, GUID: guid3, SystemID: "0002c90300a1b2c3", LogicalState: "Active"}, "Switch")
+	}
+
+	tests := []struct {
+		name    string
+		port    string
+		force   bool
+		wantErr bool
+	}{
+		{name: "shared GUID without port", force: true, wantErr: true},
+		{name: "switch port without force", port: "0002c90300a1b2c3_2", wantErr: true},
+		{name: "unknown port", port: "0002c90300a1b2c3_9", force: true, wantErr: true},
+		{name: "switch port with force", port: "0002c90300a1b2c3_2", force: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := u.DisablePort(guid3, tt.port, tt.force)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %+v", res)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to disable port: %v", err)
+			}
+			if res.Port != tt.port {
+				t.Errorf("disabled port %s, expected %s", res.Port, tt.port)
+			}
+		})
+	}
+
+	if p, _ := srv.Port("0002c90300a1b2c3_1"); p.LogicalState != "Active" {
+		t.Errorf("port 0002c90300a1b2c3_1 is %s, expected Active", p.LogicalState)
+	}
+	if p, _ := srv.Port("0002c90300a1b2c3_2"); p.LogicalState != "Down" {
+		t.Errorf("port 0002c90300a1b2c3_2 is %s, expected Down", p.LogicalState)
+	}
+}
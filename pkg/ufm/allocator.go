@@ -0,0 +1,217 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufm
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PKeyRange is an inclusive range of pkeys, e.g. 0x0100 - 0x01FF.
+type PKeyRange struct {
+	Min int32
+	Max int32
+}
+
+func (r PKeyRange) contains(pkey int32) bool {
+	return pkey >= r.Min && pkey <= r.Max
+}
+
+func (r PKeyRange) String() string {
+	return fmt.Sprintf("0x%04x-0x%04x", r.Min, r.Max)
+}
+
+// PKeyAllocator picks a free pkey for the IB network created without pkey.
+type PKeyAllocator struct {
+	// The range to pick the pkeys from; default 0x0001 - 0x7FFE.
+	Range PKeyRange
+	// The pkeys in the reserved ranges are never picked; DefaultPKey is always reserved.
+	Reserved []PKeyRange
+	// How many pkeys are tried if the picked one is taken by others concurrently; default 3,
+	// and at least one is tried.
+	MaxAttempts int
+
+	mutex sync.Mutex
+	rand  *rand.Rand
+}
+
+// DefaultPKeyAllocator picks the pkeys from all the valid ones except DefaultPKey.
+func DefaultPKeyAllocator() *PKeyAllocator {
+	return &PKeyAllocator{
+		Range:       PKeyRange{Min: 0x0001, Max: DefaultPKey - 1},
+		MaxAttempts: 3,
+	}
+}
+
+// pick returns a random free pkey in the range which is neither used, reserved nor excluded;
+// the random pick makes the concurrent creators unlikely to conflict.
+func (a *PKeyAllocator) pick(used map[int32]bool, excluded map[int32]bool) (int32, bool) {
+	var free []int32
+	for pkey := a.Range.Min; pkey <= a.Range.Max; pkey++ {
+		if pkey == DefaultPKey || !IsPKeyValid(pkey) || pkey == 0 || used[pkey] || excluded[pkey] || a.isReserved(pkey) {
+			continue
+		}
+		free = append(free, pkey)
+	}
+	if len(free) == 0 {
+		return 0, false
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.rand == nil {
+		a.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	return free[a.rand.Intn(len(free))], true
+}
+
+func (a *PKeyAllocator) isReserved(pkey int32) bool {
+	for _, r := range a.Reserved {
+		if r.contains(pkey) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParsePKeyRanges parses the comma separated pkey ranges, e.g. "0x100-0x1ff,0x7f00";
+// a single pkey is a range of itself.
+func ParsePKeyRanges(s string) ([]PKeyRange, error) {
+	var res []PKeyRange
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		parts := strings.SplitN(item, "-", 2)
+		min, err := ParsePkey(strings.TrimSpace(parts[0]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid pkey range %q: %v", item, err)
+		}
+		max := min
+		if len(parts) == 2 {
+			if max, err = ParsePkey(strings.TrimSpace(parts[1])); err != nil {
+				return nil, fmt.Errorf("invalid pkey range %q: %v", item, err)
+			}
+		}
+		if min > max {
+			return nil, fmt.Errorf("invalid pkey range %q: min is larger than max", item)
+		}
+		res = append(res, PKeyRange{Min: min, Max: max})
+	}
+
+	return res, nil
+}
+
+// claimPKey picks a free pkey for the IB network and adds its GUIDs to the pkey. The pkey is
+// re-checked before adding the GUIDs, and verified after that; if others took the pkey
+// concurrently, the GUIDs are removed from it and another pkey is tried.
+func (u *UFM) claimPKey(ctx context.Context, ib *IBNetwork, jo *jobOptions) *UFMError {
+	attempts := u.allocator.MaxAttempts
+	if attempts <= 0 {
+		attempts = 1
+	}
+
+	// The claim is verified after adding the GUIDs, so their jobs are always waited.
+	jo = jo.waited()
+
+	excluded := map[int32]bool{}
+	for i := 0; i < attempts; i++ {
+		pkeys, ufmErr := u.listQoS(ctx)
+		if ufmErr != nil {
+			return ufmErr
+		}
+		used := map[int32]bool{}
+		for pkeyStr := range pkeys {
+			if pkey, err := ParsePkey(pkeyStr); err == nil {
+				used[pkey] = true
+			}
+		}
+
+		pkey, found := u.allocator.pick(used, excluded)
+		if !found {
+			return &UFMError{
+				Code:    ConflictErr,
+				Message: fmt.Sprintf("no free pkey in %s", u.allocator.Range),
+			}
+		}
+		excluded[pkey] = true
+
+		// Re-check the pkey, it may be created after listing.
+		if _, ufmErr := u.getIBNetwork(ctx, pkey); ufmErr == nil {
+			continue
+		} else if !ufmErr.IsNotFound() {
+			return ufmErr
+		}
+
+		ib.PKey = pkey
+		if ufmErr := u.addGuids(ctx, ib, jo); ufmErr != nil {
+			u.releasePKey(ib, jo)
+			return ufmErr
+		}
+
+		cur, ufmErr := u.getIBNetwork(ctx, pkey)
+		if ufmErr != nil {
+			u.releasePKey(ib, jo)
+			return ufmErr
+		}
+		if !isClaimedBy(cur, ib) {
+			// Others won the pkey; never keep it in ib.PKey, or the rollback deletes their pkey.
+			ufmErr := u.deleteGuids(ctx, ib, jo)
+			ib.PKey = 0
+			if ufmErr != nil {
+				return ufmErr
+			}
+			continue
+		}
+
+		return nil
+	}
+
+	ib.PKey = 0
+	return &UFMError{
+		Code:    ConflictErr,
+		Message: fmt.Sprintf("failed to allocate pkey after %d attempts, the pkeys are taken concurrently", attempts),
+	}
+}
+
+// releasePKey removes the GUIDs added by a failed claim from the pkey, and resets ib.PKey, so the
+// pkey is not taken as owned; it's best-effort, as the claim has failed anyway.
+func (u *UFM) releasePKey(ib *IBNetwork, jo *jobOptions) {
+	// The context of the claim may be done, e.g. timeout; release by a new one.
+	ctx, cancel := context.WithTimeout(context.Background(), rollbackTimeout)
+	defer cancel()
+
+	_ = u.deleteGuids(ctx, ib, jo)
+	ib.PKey = 0
+}
+
+// isClaimedBy returns true if the pkey has only the GUIDs and the name of the IB network.
+func isClaimedBy(cur, ib *IBNetwork) bool {
+	if cur.Name != "" && ib.Name != "" && cur.Name != ib.Name {
+		return false
+	}
+
+	_, removed, _ := diffGUIDs(cur.GUIDs, memberGUIDs(ib.GUIDMembers()))
+	return len(removed) == 0
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufm_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/openbce/kperf/pkg/ufm"
+	"github.com/openbce/kperf/pkg/ufm/ufmtest"
+)
+
+func TestAllocatePKey(t *testing.T) {
+	u, srv := newUFM(t, "")
+	srv.AddIBNetwork(&ufm.IBNetwork{PKey: 0x100})
+
+	ib := &ufm.IBNetwork{Name: "tenant-a", GUIDs: []string{guid1}}
+	if err := u.CreateIBNetwork(ib); err != nil {
+		t.Fatalf("failed to create IB network: %v", err)
+	}
+	if ib.PKey == 0 || ib.PKey == 0x100 || ib.PKey == ufm.DefaultPKey {
+		t.Fatalf("allocated pkey 0x%x, expected a free one", ib.PKey)
+	}
+	got, found := srv.GetIBNetwork(ib.PKey)
+	if !found || got.Name != "tenant-a" {
+		t.Errorf("allocated pkey 0x%x is not created", ib.PKey)
+	}
+}
+
+func TestAllocatePKeyFailed(t *testing.T) {
+	u, srv := newUFM(t, "")
+	// The GUIDs are added, but the job reports failure.
+	srv.SetAsyncGUIDs(1)
+	srv.FailJobs("port is down")
+
+	ib := &ufm.IBNetwork{Name: "tenant-a", GUIDs: []string{guid1}}
+	if err := u.CreateIBNetwork(ib); err == nil {
+		t.Fatalf("expected error of the failed job")
+	}
+	if ib.PKey != 0 {
+		t.Errorf("pkey 0x%x is kept after the failed claim", ib.PKey)
+	}
+	for _, pkey := range srv.PKeys() {
+		if n, _ := srv.GetIBNetwork(pkey); len(n.GUIDs) != 0 {
+			t.Errorf("GUIDs %v are left in pkey 0x%x", n.GUIDs, pkey)
+		}
+	}
+}
+
+func TestAllocatePKeyLostRace(t *testing.T) {
+	u, srv := newUFM(t, "")
+	srv.SetAsyncGUIDs(1)
+
+	var winner int32
+	srv.AfterRequest(http.MethodPost, "/ufmRest/resources/pkeys", func(r ufmtest.Request) {
+		req := struct {
+			PKey string `json:"pkey"`
+		}{}
+		_ = json.Unmarshal(r.Body, &req)
+		winner, _ = ufm.ParsePkey(req.PKey)
+		// Another creator takes the pkey concurrently, and removing the GUIDs from it fails.
+		srv.AddIBNetwork(&ufm.IBNetwork{Name: "tenant-b", PKey: winner, GUIDs: []string{guid2}})
+		srv.FailJobs("port is down")
+	})
+
+	ib := &ufm.IBNetwork{Name: "tenant-a", GUIDs: []string{guid1}}
+	if err := u.CreateIBNetwork(ib); err == nil {
+		t.Fatalf("expected error of the failed cleanup")
+	}
+	if ib.PKey != 0 {
+		t.Errorf("pkey 0x%x of the winner is kept after the lost race", ib.PKey)
+	}
+	got, found := srv.GetIBNetwork(winner)
+	if !found || got.Name != "tenant-b" {
+		t.Errorf("pkey 0x%x of the winner is deleted by the rollback", winner)
+	}
+}
@@ -0,0 +1,279 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ApplyAction is a change to an IB network for its desired state.
+type ApplyAction string
+
+const (
+	// CreateAction creates the IB network with all the GUIDs and QoS.
+	CreateAction ApplyAction = "create"
+	// UpdateGUIDsAction re-adds the GUIDs to update the name, ip_over_ib, membership and index0
+	// of the IB network.
+	UpdateGUIDsAction ApplyAction = "update-guids"
+	AddGUIDsAction    ApplyAction = "add-guids"
+	RemoveGUIDsAction ApplyAction = "remove-guids"
+	UpdateQoSAction   ApplyAction = "update-qos"
+	CreateSharpAction ApplyAction = "create-sharp"
+	DeleteSharpAction ApplyAction = "delete-sharp"
+)
+
+// ApplyStep is a step of the ApplyPlan.
+type ApplyStep struct {
+	Action ApplyAction `json:"action"`
+	// The GUIDs added or removed by the step.
+	GUIDs []string `json:"guids,omitempty"`
+	// The changed fields, e.g. "mtu: 2 -> 4".
+	Changes []string `json:"changes,omitempty"`
+}
+
+func (s ApplyStep) String() string {
+	switch s.Action {
+	case CreateAction:
+		return fmt.Sprintf("create with %d GUIDs", len(s.GUIDs))
+	case AddGUIDsAction, RemoveGUIDsAction:
+		return fmt.Sprintf("%s %s", s.Action, strings.Join(s.GUIDs, ","))
+	case CreateSharpAction, DeleteSharpAction:
+		return string(s.Action)
+	}
+
+	return fmt.Sprintf("%s %s", s.Action, strings.Join(s.Changes, "; "))
+}
+
+// ApplyPlan is the steps to change the current IB network to the desired one.
+type ApplyPlan struct {
+	Desired *IBNetwork `json:"desired"`
+	// Current is nil if the IB network does not exist.
+	Current *IBNetwork  `json:"current,omitempty"`
+	Steps   []ApplyStep `json:"steps"`
+}
+
+// IsEmpty returns true if the IB network is already in the desired state.
+func (p *ApplyPlan) IsEmpty() bool {
+	return len(p.Steps) == 0
+}
+
+func (u *UFM) Plan(ib *IBNetwork) (*ApplyPlan, *UFMError) {
+	return u.PlanWithContext(context.Background(), ib)
+}
+
+// PlanWithContext compares the desired IB network with the one in UFM, and returns the steps
+// to reach the desired state; nothing is changed in UFM.
+func (u *UFM) PlanWithContext(ctx context.Context, ib *IBNetwork) (*ApplyPlan, *UFMError) {
+	cur, ufmErr := u.GetIBNetworkWithContext(ctx, ib.PKey)
+	if ufmErr != nil {
+		if !ufmErr.IsNotFound() {
+			return nil, ufmErr
+		}
+		return &ApplyPlan{
+			Desired: ib,
+			Steps:   []ApplyStep{{Action: CreateAction, GUIDs: memberGUIDs(ib.GUIDMembers())}},
+		}, nil
+	}
+
+	return buildApplyPlan(cur, ib), nil
+}
+
+func buildApplyPlan(cur, ib *IBNetwork) *ApplyPlan {
+	plan := &ApplyPlan{Desired: ib, Current: cur}
+
+	// The name and ip_over_ib are set by adding the GUIDs, so all the desired GUIDs are re-added;
+	// the pkey is updated without GUIDs if there is none. The name is kept if not set.
+	desiredGUIDs := memberGUIDs(ib.GUIDMembers())
+	var pkeyChanges []string
+	if ib.Name != "" && cur.Name != ib.Name {
+		pkeyChanges = append(pkeyChanges, fmt.Sprintf("name: %s -> %s", cur.Name, ib.Name))
+	}
+	if cur.IPOverIB != ib.IPOverIB {
+		pkeyChanges = append(pkeyChanges, fmt.Sprintf("ip_over_ib: %t -> %t", cur.IPOverIB, ib.IPOverIB))
+	}
+	if len(pkeyChanges) != 0 {
+		plan.Steps = append(plan.Steps, ApplyStep{Action: UpdateGUIDsAction, GUIDs: desiredGUIDs, Changes: pkeyChanges})
+	} else if guids, changes := diffMembers(cur, ib); len(guids) != 0 {
+		plan.Steps = append(plan.Steps, ApplyStep{Action: UpdateGUIDsAction, GUIDs: guids, Changes: changes})
+	}
+
+	// The added GUIDs are re-added with the others by the update of the pkey if any.
+	added, removed, _ := diffGUIDs(memberGUIDs(cur.GUIDMembers()), desiredGUIDs)
+	if len(added) != 0 && len(pkeyChanges) == 0 {
+		plan.Steps = append(plan.Steps, ApplyStep{Action: AddGUIDsAction, GUIDs: added})
+	}
+	if len(removed) != 0 {
+		plan.Steps = append(plan.Steps, ApplyStep{Action: RemoveGUIDsAction, GUIDs: removed})
+	}
+
+	var changes []string
+	if ParseMTU(cur.MTU) != ParseMTU(ib.MTU) {
+		changes = append(changes, fmt.Sprintf("mtu: %d -> %d", ParseMTU(cur.MTU), ParseMTU(ib.MTU)))
+	}
+	if cur.ServiceLevel != ib.ServiceLevel {
+		changes = append(changes, fmt.Sprintf("service_level: %d -> %d", cur.ServiceLevel, ib.ServiceLevel))
+	}
+	if cur.RateLimit != ib.RateLimit {
+		changes = append(changes, fmt.Sprintf("rate_limit: %.2f -> %.2f", cur.RateLimit, ib.RateLimit))
+	}
+	if len(changes) != 0 {
+		plan.Steps = append(plan.Steps, ApplyStep{Action: UpdateQoSAction, Changes: changes})
+	}
+
+	if !cur.EnableSharp && ib.EnableSharp {
+		plan.Steps = append(plan.Steps, ApplyStep{Action: CreateSharpAction})
+	}
+	if cur.EnableSharp && !ib.EnableSharp {
+		plan.Steps = append(plan.Steps, ApplyStep{Action: DeleteSharpAction})
+	}
+
+	return plan
+}
+
+func (u *UFM) Apply(plan *ApplyPlan, opts ...JobOption) *UFMError {
+	return u.ApplyWithContext(context.Background(), plan, opts...)
+}
+
+// ApplyWithContext executes the steps of the plan in order, and stops at the first failed one;
+// the jobs of UFM are waited unless WithWait(false).
+func (u *UFM) ApplyWithContext(ctx context.Context, plan *ApplyPlan, opts ...JobOption) *UFMError {
+	jo := newJobOptions(opts...)
+	ib := plan.Desired
+	for _, step := range plan.Steps {
+		var ufmErr *UFMError
+		switch step.Action {
+		case CreateAction:
+			ufmErr = u.createIBNetwork(ctx, ib, jo)
+		case UpdateGUIDsAction, AddGUIDsAction:
+			ufmErr = u.addGuids(ctx, ib.withGUIDs(step.GUIDs), jo)
+		case RemoveGUIDsAction:
+			ufmErr = u.deleteGuids(ctx, ib.withGUIDs(step.GUIDs), jo)
+		case UpdateQoSAction:
+			ufmErr = u.patchQoS(ctx, ib, SetStrategy, jo)
+		case CreateSharpAction:
+			ufmErr = u.createSharpReservation(ctx, ib)
+		case DeleteSharpAction:
+			ufmErr = u.deleteSharpReservation(ctx, ib.PKey)
+		default:
+			ufmErr = &UFMError{
+				Code:    UnknownErr,
+				Message: fmt.Sprintf("unknown apply action %q", step.Action),
+			}
+		}
+		if ufmErr != nil {
+			return wrapError(ufmErr, "failed to %s of PKey 0x%04X", step.Action, ib.PKey)
+		}
+	}
+
+	return nil
+}
+
+// withGUIDs returns a copy of the IB network with the GUIDs, and their members.
+func (ib *IBNetwork) withGUIDs(guids []string) *IBNetwork {
+	set := map[string]bool{}
+	for _, g := range guids {
+		set[normalizeGUID(g)] = true
+	}
+
+	res := *ib
+	res.GUIDs = guids
+	res.Members = nil
+	for _, m := range ib.Members {
+		if set[normalizeGUID(m.GUID)] {
+			res.Members = append(res.Members, m)
+		}
+	}
+
+	return &res
+}
+
+// diffMembers returns the GUIDs in both IB networks whose membership or index0 is changed.
+func diffMembers(cur, ib *IBNetwork) ([]string, []string) {
+	curMembers := map[string]GUIDMember{}
+	for _, m := range cur.GUIDMembers() {
+		curMembers[normalizeGUID(m.GUID)] = m
+	}
+
+	var guids, changes []string
+	for _, m := range ib.GUIDMembers() {
+		c, found := curMembers[normalizeGUID(m.GUID)]
+		if !found {
+			continue
+		}
+		var changed []string
+		if c.Membership != m.Membership {
+			changed = append(changed, fmt.Sprintf("membership: %s -> %s", c.Membership, m.Membership))
+		}
+		if c.Index0 != m.Index0 {
+			changed = append(changed, fmt.Sprintf("index0: %t -> %t", c.Index0, m.Index0))
+		}
+		if len(changed) != 0 {
+			guids = append(guids, m.GUID)
+			changes = append(changes, fmt.Sprintf("%s %s", m.GUID, strings.Join(changed, ", ")))
+		}
+	}
+
+	return guids, changes
+}
+
+func memberGUIDs(members []GUIDMember) []string {
+	var res []string
+	for _, m := range members {
+		res = append(res, m.GUID)
+	}
+	return res
+}
+
+// diffGUIDs compares the current GUIDs with the desired ones; the GUIDs are matched case
+// insensitively with or without the "0x" prefix.
+func diffGUIDs(cur, desired []string) (added, removed, unchanged []string) {
+	curSet := map[string]bool{}
+	for _, g := range cur {
+		curSet[normalizeGUID(g)] = true
+	}
+	desiredSet := map[string]bool{}
+	for _, g := range desired {
+		desiredSet[normalizeGUID(g)] = true
+	}
+
+	seen := map[string]bool{}
+	for _, g := range desired {
+		if seen[normalizeGUID(g)] {
+			continue
+		}
+		seen[normalizeGUID(g)] = true
+		if curSet[normalizeGUID(g)] {
+			unchanged = append(unchanged, g)
+		} else {
+			added = append(added, g)
+		}
+	}
+	for _, g := range cur {
+		if !desiredSet[normalizeGUID(g)] {
+			removed = append(removed, g)
+		}
+	}
+
+	return added, removed, unchanged
+}
+
+func normalizeGUID(guid string) string {
+	guid = strings.ToLower(guid)
+	return strings.TrimPrefix(guid, "0x")
+}
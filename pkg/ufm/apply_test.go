@@ -0,0 +1,101 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufm_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/openbce/kperf/pkg/ufm"
+)
+
+func planActions(plan *ufm.ApplyPlan) []ufm.ApplyAction {
+	var res []ufm.ApplyAction
+	for _, s := range plan.Steps {
+		res = append(res, s.Action)
+	}
+	return res
+}
+
+func TestApplyWithoutGUIDs(t *testing.T) {
+	u, srv := newUFM(t, "")
+	srv.AddIBNetwork(&ufm.IBNetwork{Name: "old", PKey: 0x100, MTU: 2, RateLimit: 2.5})
+
+	desired := &ufm.IBNetwork{Name: "new", PKey: 0x100, IPOverIB: true, MTU: 4, ServiceLevel: 1, RateLimit: 2.5}
+	plan, err := u.Plan(desired)
+	if err != nil {
+		t.Fatalf("failed to plan: %v", err)
+	}
+	actions := planActions(plan)
+	if len(actions) != 2 || actions[0] != ufm.UpdateGUIDsAction || actions[1] != ufm.UpdateQoSAction {
+		t.Fatalf("planned %v, expected update-guids and update-qos", actions)
+	}
+
+	if err := u.Apply(plan); err != nil {
+		t.Fatalf("failed to apply: %v", err)
+	}
+	ib, _ := srv.GetIBNetwork(0x100)
+	if ib.Name != "new" || !ib.IPOverIB || ib.MTU != 4 || ib.ServiceLevel != 1 {
+		t.Errorf("IB network is not updated: %+v", ib)
+	}
+
+	plan, err = u.Plan(desired)
+	if err != nil {
+		t.Fatalf("failed to plan: %v", err)
+	}
+	if !plan.IsEmpty() {
+		t.Errorf("planned %v after apply, expected nothing", planActions(plan))
+	}
+}
+
+func TestPlanKeepsUnsetName(t *testing.T) {
+	u, srv := newUFM(t, "")
+	srv.AddIBNetwork(&ufm.IBNetwork{Name: "tenant-a", PKey: 0x100, GUIDs: []string{guid1}, MTU: 2, RateLimit: 2.5})
+
+	plan, err := u.Plan(&ufm.IBNetwork{PKey: 0x100, GUIDs: []string{guid1}, MTU: 2, RateLimit: 2.5})
+	if err != nil {
+		t.Fatalf("failed to plan: %v", err)
+	}
+	if !plan.IsEmpty() {
+		t.Errorf("planned %v, expected nothing", planActions(plan))
+	}
+}
+
+func TestPlanRenameWithAddedGUIDs(t *testing.T) {
+	u, srv := newUFM(t, "")
+	srv.AddIBNetwork(&ufm.IBNetwork{Name: "old", PKey: 0x100, GUIDs: []string{guid1}, MTU: 2, RateLimit: 2.5})
+
+	plan, err := u.Plan(&ufm.IBNetwork{Name: "new", PKey: 0x100, GUIDs: []string{guid1, guid2}, MTU: 2, RateLimit: 2.5})
+	if err != nil {
+		t.Fatalf("failed to plan: %v", err)
+	}
+	// The update of the name re-adds all the GUIDs, including the added one.
+	if actions := planActions(plan); len(actions) != 1 || actions[0] != ufm.UpdateGUIDsAction {
+		t.Fatalf("planned %v, expected update-guids only", actions)
+	}
+
+	srv.ResetRequests()
+	if err := u.Apply(plan); err != nil {
+		t.Fatalf("failed to apply: %v", err)
+	}
+	if n := countRequests(srv, http.MethodPost, "/ufmRest/resources/pkeys"); n != 1 {
+		t.Errorf("posted the GUIDs %d times, expected once", n)
+	}
+	if ib, _ := srv.GetIBNetwork(0x100); ib.Name != "new" || len(ib.GUIDs) != 2 {
+		t.Errorf("IB network is not updated: %+v", ib)
+	}
+}
@@ -0,0 +1,235 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufm
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+type AuthType string
+
+const (
+	BasicAuthType   AuthType = "basic"
+	TokenAuthType   AuthType = "token"
+	SessionAuthType AuthType = "session"
+)
+
+// The prefix of UFM REST API for each authentication type.
+const (
+	basicAuthPrefix   = "/ufmRest/"
+	sessionAuthPrefix = "/ufmRestV2/"
+	tokenAuthPrefix   = "/ufmRestV3/"
+)
+
+// Authenticator sets the credentials of the requests to UFM.
+type Authenticator interface {
+	// Authenticate sets the credentials of req; the client is used if the
+	// authenticator has to login UFM before the request.
+	Authenticate(ctx context.Context, client *http.Client, req *http.Request) error
+}
+
+// invalidator is implemented by the Authenticators whose credentials may expire,
+// the ufmclient invalidates the credentials and retries once if UFM returns 401.
+type invalidator interface {
+	Invalidate()
+}
+
+// loggerSetter is implemented by the Authenticators which log, the ufmclient sets
+// its logger to them, so their logs go to the logger of WithLogger.
+type loggerSetter interface {
+	setLogger(logger zerolog.Logger)
+}
+
+func ParseAuthType(t string) (AuthType, error) {
+	switch strings.ToLower(t) {
+	case string(BasicAuthType):
+		return BasicAuthType, nil
+	case string(TokenAuthType):
+		return TokenAuthType, nil
+	case string(SessionAuthType):
+		return SessionAuthType, nil
+	}
+
+	return "", fmt.Errorf("unknown auth type %s, one of 'basic', 'token' or 'session'", t)
+}
+
+// BasicAuth authenticates each request by username and password.
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+func (a *BasicAuth) Authenticate(_ context.Context, _ *http.Client, req *http.Request) error {
+	req.SetBasicAuth(a.Username, a.Password)
+	return nil
+}
+
+func (a *BasicAuth) String() string {
+	return fmt.Sprintf("basic auth of %s", a.Username)
+}
+
+// TokenAuth authenticates each request by the access token generated in UFM.
+type TokenAuth struct {
+	Token string
+}
+
+func (a *TokenAuth) Authenticate(_ context.Context, _ *http.Client, req *http.Request) error {
+	rewriteAuthPrefix(req, tokenAuthPrefix)
+	req.Header.Set("Authorization", "Basic "+a.Token)
+	return nil
+}
+
+func (a *TokenAuth) String() string {
+	return "token auth"
+}
+
+// SessionAuth logins UFM once by username and password, and authenticates the
+// following requests by the session cookie; it re-logins if the session expired.
+type SessionAuth struct {
+	Username string
+	Password string
+
+	mutex   sync.Mutex
+	cookies []*http.Cookie
+	logger  *zerolog.Logger
+}
+
+func (a *SessionAuth) Authenticate(ctx context.Context, client *http.Client, req *http.Request) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if len(a.cookies) == 0 {
+		if err := a.login(ctx, client, req.URL); err != nil {
+			return err
+		}
+	}
+
+	rewriteAuthPrefix(req, sessionAuthPrefix)
+	for _, c := range a.cookies {
+		req.AddCookie(c)
+	}
+
+	return nil
+}
+
+func (a *SessionAuth) Invalidate() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.cookies = nil
+}
+
+func (a *SessionAuth) setLogger(logger zerolog.Logger) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.logger = &logger
+}
+
+func (a *SessionAuth) String() string {
+	return fmt.Sprintf("session auth of %s", a.Username)
+}
+
+func (a *SessionAuth) login(ctx context.Context, client *http.Client, target *url.URL) error {
+	loginURL := fmt.Sprintf("%s://%s/dologin", target.Scheme, target.Host)
+	logger := &log.Logger
+	if a.logger != nil {
+		logger = a.logger
+	}
+	logger.Debug().Msgf("Http ufmclient login: url %s, username %s", loginURL, a.Username)
+
+	form := url.Values{}
+	form.Set("httpd_username", a.Username)
+	form.Set("httpd_password", a.Password)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, loginURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	// UFM redirects to the home page after login, keep the response with the session cookie.
+	loginClient := *client
+	loginClient.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	resp, err := loginClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = ioutil.ReadAll(resp.Body)
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("failed to login UFM as %s, http status (%d): %s",
+			a.Username, resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	if len(resp.Cookies()) == 0 {
+		return fmt.Errorf("failed to login UFM as %s, no session cookie", a.Username)
+	}
+
+	a.cookies = resp.Cookies()
+	return nil
+}
+
+// rewriteAuthPrefix rewrites the prefix of UFM REST API according to the authentication type.
+func rewriteAuthPrefix(req *http.Request, prefix string) {
+	if strings.HasPrefix(req.URL.Path, basicAuthPrefix) {
+		req.URL.Path = prefix + strings.TrimPrefix(req.URL.Path, basicAuthPrefix)
+	}
+}
+
+// newAuthenticator creates the Authenticator according to the config; the auth type is
+// "token" if only UFM_TOKEN is provided, otherwise "basic" by default.
+func newAuthenticator(conf *UFMConfig) (Authenticator, error) {
+	authType := BasicAuthType
+	if conf.AuthType != "" {
+		t, err := ParseAuthType(conf.AuthType)
+		if err != nil {
+			return nil, err
+		}
+		authType = t
+	} else if conf.Token != "" && conf.Username == "" {
+		authType = TokenAuthType
+	}
+
+	switch authType {
+	case TokenAuthType:
+		if conf.Token == "" {
+			return nil, fmt.Errorf("missing required field for ufm token auth [\"token\"]")
+		}
+		return &TokenAuth{Token: conf.Token}, nil
+	case SessionAuthType:
+		if conf.Username == "" || conf.Password == "" {
+			return nil, fmt.Errorf("missing one or more required fields for ufm session auth [\"username\", \"password\"]")
+		}
+		return &SessionAuth{Username: conf.Username, Password: conf.Password}, nil
+	default:
+		if conf.Username == "" || conf.Password == "" {
+			return nil, fmt.Errorf("missing one or more required fields for ufm basic auth [\"username\", \"password\"]")
+		}
+		return &BasicAuth{Username: conf.Username, Password: conf.Password}, nil
+	}
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufm
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	envv6 "github.com/caarlos0/env/v6"
+)
+
+const (
+	httpsProto = "https"
+	httpProto  = "http"
+)
+
+type UFMConfig struct {
+	Username    string `env:"UFM_USERNAME"`    // Username of ufm
+	Password    string `env:"UFM_PASSWORD"`    // Password of ufm
+	Address     string `env:"UFM_ADDRESS"`     // IP address or hostname of ufm server
+	Port        int    `env:"UFM_PORT"`        // REST API port of ufm
+	HTTPSchema  string `env:"UFM_HTTP_SCHEMA"` // http or https
+	Certificate string `env:"UFM_CERTIFICATE"` // Certificate of ufm
+	Token       string `env:"UFM_TOKEN"`       // Access token of ufm
+	AuthType    string `env:"UFM_AUTH_TYPE"`   // basic, token or session
+
+	CertificateFile string `env:"UFM_CERTIFICATE_FILE"`     // Path of the CA bundle of ufm
+	ClientCertFile  string `env:"UFM_CLIENT_CERT_FILE"`     // Path of the client certificate for mTLS
+	ClientKeyFile   string `env:"UFM_CLIENT_KEY_FILE"`      // Path of the client key for mTLS
+	ServerName      string `env:"UFM_SERVER_NAME"`          // Server name to verify the certificate of ufm
+	TLSMinVersion   string `env:"UFM_TLS_MIN_VERSION"`      // Minimum TLS version, 1.2 or 1.3
+	Insecure        bool   `env:"UFM_INSECURE_SKIP_VERIFY"` // Skip the verification of ufm certificate
+
+	RetryMaxAttempts int           `env:"UFM_RETRY_MAX_ATTEMPTS"` // Max attempts of a request to ufm, 1 means no retry
+	RetryBackoff     time.Duration `env:"UFM_RETRY_BACKOFF"`      // Initial backoff between the retries, e.g. 500ms
+
+	PKeyRange    string `env:"UFM_PKEY_RANGE"`    // Range to allocate the pkeys from, e.g. 0x100-0x1ff
+	PKeyReserved string `env:"UFM_PKEY_RESERVED"` // Reserved pkey ranges never allocated, e.g. 0x1-0xff,0x7f00-0x7ffe
+}
+
+// ConfigError reports all the invalid fields of UFMConfig.
+type ConfigError struct {
+	Errors []string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("invalid ufm config: %s", strings.Join(e.Errors, "; "))
+}
+
+// UFMConfigFromEnv reads the UFMConfig from the environment values, e.g. UFM_ADDRESS.
+func UFMConfigFromEnv() (UFMConfig, error) {
+	conf := UFMConfig{}
+	if err := conf.OverrideFromEnv(); err != nil {
+		return conf, err
+	}
+
+	return conf, nil
+}
+
+// OverrideFromEnv overrides the fields by the environment values which are set; the
+// fields without environment value are kept.
+func (c *UFMConfig) OverrideFromEnv() error {
+	return envv6.Parse(c)
+}
+
+// Validate checks all the fields of the config and reports every invalid one at once;
+// the empty HTTPSchema and Port are valid, which are set to the default of ufm.
+func (c *UFMConfig) Validate() error {
+	errs := c.validate(true)
+	if len(errs) != 0 {
+		return &ConfigError{Errors: errs}
+	}
+
+	return nil
+}
+
+func (c *UFMConfig) validate(withAuth bool) []string {
+	var errs []string
+
+	if c.Address == "" {
+		errs = append(errs, "address is required")
+	}
+	if c.HTTPSchema != "" && !strings.EqualFold(c.HTTPSchema, httpsProto) && !strings.EqualFold(c.HTTPSchema, httpProto) {
+		errs = append(errs, fmt.Sprintf("schema %q is not one of 'http' or 'https'", c.HTTPSchema))
+	}
+	if c.Port < 0 || c.Port > 65535 {
+		errs = append(errs, fmt.Sprintf("port %d is out of range 1 - 65535", c.Port))
+	}
+	if _, err := ParseTLSVersion(c.TLSMinVersion); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if c.RetryMaxAttempts < 0 {
+		errs = append(errs, fmt.Sprintf("retry max attempts %d is negative", c.RetryMaxAttempts))
+	}
+	if c.RetryBackoff < 0 {
+		errs = append(errs, fmt.Sprintf("retry backoff %v is negative", c.RetryBackoff))
+	}
+	if ranges, err := ParsePKeyRanges(c.PKeyRange); err != nil {
+		errs = append(errs, err.Error())
+	} else if len(ranges) > 1 {
+		errs = append(errs, fmt.Sprintf("pkey range %q has more than one range", c.PKeyRange))
+	}
+	if _, err := ParsePKeyRanges(c.PKeyReserved); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if withAuth {
+		if _, err := newAuthenticator(c); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	return errs
+}
+
+// setDefaults sets httpSchema and port to ufm default if missing.
+func (c *UFMConfig) setDefaults() {
+	c.HTTPSchema = strings.ToLower(c.HTTPSchema)
+	if c.HTTPSchema == "" {
+		c.HTTPSchema = httpsProto
+	}
+	if c.Port == 0 {
+		if c.HTTPSchema == httpsProto {
+			c.Port = 443
+		} else {
+			c.Port = 80
+		}
+	}
+}
+
+func (c *UFMConfig) tlsConfig() (*TLSConfig, error) {
+	tlsMinVersion, err := ParseTLSVersion(c.TLSMinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TLSConfig{
+		InsecureSkipVerify: c.Insecure,
+		CAFile:             c.CertificateFile,
+		CAData:             c.Certificate,
+		CertFile:           c.ClientCertFile,
+		KeyFile:            c.ClientKeyFile,
+		ServerName:         c.ServerName,
+		MinVersion:         tlsMinVersion,
+	}, nil
+}
+
+func (c *UFMConfig) retryPolicy() RetryPolicy {
+	retryPolicy := DefaultRetryPolicy()
+	if c.RetryMaxAttempts > 0 {
+		retryPolicy.MaxAttempts = c.RetryMaxAttempts
+	}
+	if c.RetryBackoff > 0 {
+		retryPolicy.InitialBackoff = c.RetryBackoff
+	}
+
+	return retryPolicy
+}
+
+func (c *UFMConfig) pkeyAllocator() *PKeyAllocator {
+	allocator := DefaultPKeyAllocator()
+	if ranges, _ := ParsePKeyRanges(c.PKeyRange); len(ranges) != 0 {
+		allocator.Range = ranges[0]
+	}
+	allocator.Reserved, _ = ParsePKeyRanges(c.PKeyReserved)
+
+	return allocator
+}
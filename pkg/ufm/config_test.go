@@ -0,0 +1,126 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufm_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/openbce/kperf/pkg/ufm"
+	"github.com/openbce/kperf/pkg/ufm/ufmtest"
+)
+
+func validConfig() ufm.UFMConfig {
+	return ufm.UFMConfig{Address: "ufm.example.com", Username: "admin", Password: "123456"}
+}
+
+func TestConfigError(t *testing.T) {
+	tests := []struct {
+		name string
+		set  func(conf *ufm.UFMConfig)
+		want []string
+	}{
+		{name: "valid", set: func(conf *ufm.UFMConfig) {}},
+		{name: "address", set: func(conf *ufm.UFMConfig) { conf.Address = "" }, want: []string{"address is required"}},
+		{name: "schema", set: func(conf *ufm.UFMConfig) { conf.HTTPSchema = "ftp" }, want: []string{`schema "ftp"`}},
+		{name: "port", set: func(conf *ufm.UFMConfig) { conf.Port = 65536 }, want: []string{"port 65536"}},
+		{name: "negative port", set: func(conf *ufm.UFMConfig) { conf.Port = -1 }, want: []string{"port -1"}},
+		{name: "tls version", set: func(conf *ufm.UFMConfig) { conf.TLSMinVersion = "1.9" }, want: []string{"unknown TLS version 1.9"}},
+		{name: "credentials", set: func(conf *ufm.UFMConfig) { conf.Password = "" }, want: []string{"basic auth"}},
+		{
+			name: "all",
+			set: func(conf *ufm.UFMConfig) {
+				*conf = ufm.UFMConfig{
+					HTTPSchema:       "ftp",
+					Port:             65536,
+					TLSMinVersion:    "1.9",
+					RetryMaxAttempts: -1,
+					RetryBackoff:     -time.Second,
+					PKeyRange:        "0x200-0x100",
+					PKeyReserved:     "abc",
+				}
+			},
+			want: []string{
+				"address is required",
+				`schema "ftp"`,
+				"port 65536",
+				"unknown TLS version 1.9",
+				"retry max attempts -1",
+				"retry backoff -1s",
+				`invalid pkey range "0x200-0x100"`,
+				`invalid pkey range "abc"`,
+				"basic auth",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := validConfig()
+			tt.set(&conf)
+
+			err := conf.Validate()
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var confErr *ufm.ConfigError
+			if !errors.As(err, &confErr) {
+				t.Fatalf("error %v is not ConfigError", err)
+			}
+			if len(confErr.Errors) != len(tt.want) {
+				t.Fatalf("got errors %q, expected %d errors", confErr.Errors, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(confErr.Errors[i], want) {
+					t.Errorf("error %q does not contain %q", confErr.Errors[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewUFMWithInvalidConfig(t *testing.T) {
+	conf := ufm.UFMConfig{HTTPSchema: "ftp", Port: 65536}
+
+	_, err := ufm.NewUFMWithConfig(conf)
+	var confErr *ufm.ConfigError
+	if !errors.As(err, &confErr) {
+		t.Fatalf("error %v is not ConfigError", err)
+	}
+	// The address, schema, port and credentials are reported at once.
+	if len(confErr.Errors) != 4 {
+		t.Errorf("got errors %q, expected 4 errors", confErr.Errors)
+	}
+
+	// The credentials are not required with a custom client.
+	client, ufmErr := ufm.NewClient(false, &ufm.TokenAuth{Token: ufmtest.DefaultToken}, nil, ufm.WithLogger(zerolog.Nop()))
+	if ufmErr != nil {
+		t.Fatalf("failed to create ufmclient: %v", ufmErr)
+	}
+	_, err = ufm.NewUFMWithConfig(ufm.UFMConfig{Address: "ufm.example.com"}, ufm.WithClient(client))
+	if err != nil {
+		t.Errorf("unexpected error with a custom client: %v", err)
+	}
+}
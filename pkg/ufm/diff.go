@@ -0,0 +1,136 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufm
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+type DiffType string
+
+const (
+	// The IB network is only in the target.
+	DiffAdded DiffType = "added"
+	// The IB network is only in the source.
+	DiffRemoved  DiffType = "removed"
+	DiffModified DiffType = "modified"
+)
+
+// FieldDiff is a changed field of the IB network, e.g. mtu from 2 to 4.
+type FieldDiff struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// IBNetworkDiff is the difference of an IB network between the source and the target.
+type IBNetworkDiff struct {
+	PKey         int32       `json:"pkey"`
+	Name         string      `json:"name"`
+	Type         DiffType    `json:"type"`
+	Fields       []FieldDiff `json:"fields,omitempty"`
+	GUIDsAdded   []string    `json:"guids_added,omitempty"`
+	GUIDsRemoved []string    `json:"guids_removed,omitempty"`
+}
+
+// DiffIBNetworks compares the IB networks of the source with the ones of the target by pkey,
+// and returns the differences ordered by pkey; the identical IB networks are omitted.
+func DiffIBNetworks(from, to []*IBNetwork) []*IBNetworkDiff {
+	fromSet := map[int32]*IBNetwork{}
+	for _, ib := range from {
+		fromSet[ib.PKey] = ib
+	}
+	toSet := map[int32]*IBNetwork{}
+	for _, ib := range to {
+		toSet[ib.PKey] = ib
+	}
+
+	var res []*IBNetworkDiff
+	for pkey, f := range fromSet {
+		t, found := toSet[pkey]
+		if !found {
+			res = append(res, &IBNetworkDiff{PKey: pkey, Name: f.Name, Type: DiffRemoved})
+			continue
+		}
+		if d := diffIBNetwork(f, t); d != nil {
+			res = append(res, d)
+		}
+	}
+	for pkey, t := range toSet {
+		if _, found := fromSet[pkey]; !found {
+			res = append(res, &IBNetworkDiff{
+				PKey:       pkey,
+				Name:       t.Name,
+				Type:       DiffAdded,
+				GUIDsAdded: memberGUIDs(t.GUIDMembers()),
+			})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].PKey < res[j].PKey })
+
+	return res
+}
+
+func diffIBNetwork(from, to *IBNetwork) *IBNetworkDiff {
+	res := &IBNetworkDiff{PKey: to.PKey, Name: to.Name, Type: DiffModified}
+	if res.Name == "" {
+		res.Name = from.Name
+	}
+
+	addField := func(field, f, t string) {
+		if f != t {
+			res.Fields = append(res.Fields, FieldDiff{Field: field, From: f, To: t})
+		}
+	}
+	// The name is optional, e.g. in a manifest on either side; it's kept by apply if not set.
+	if from.Name != "" && to.Name != "" {
+		addField("name", from.Name, to.Name)
+	}
+	addField("ip_over_ib", strconv.FormatBool(from.IPOverIB), strconv.FormatBool(to.IPOverIB))
+	addField("mtu", strconv.Itoa(int(ParseMTU(from.MTU))), strconv.Itoa(int(ParseMTU(to.MTU))))
+	addField("service_level", strconv.Itoa(int(from.ServiceLevel)), strconv.Itoa(int(to.ServiceLevel)))
+	addField("rate_limit", fmt.Sprintf("%.2f", from.RateLimit), fmt.Sprintf("%.2f", to.RateLimit))
+	addField("enable_sharp", strconv.FormatBool(from.EnableSharp), strconv.FormatBool(to.EnableSharp))
+
+	fromMembers := map[string]GUIDMember{}
+	for _, m := range from.GUIDMembers() {
+		fromMembers[normalizeGUID(m.GUID)] = m
+	}
+	for _, m := range to.GUIDMembers() {
+		if f, found := fromMembers[normalizeGUID(m.GUID)]; found {
+			addField(fmt.Sprintf("members[%s]", m.GUID), describeMember(f), describeMember(m))
+		}
+	}
+
+	res.GUIDsAdded, res.GUIDsRemoved, _ = diffGUIDs(memberGUIDs(from.GUIDMembers()), memberGUIDs(to.GUIDMembers()))
+
+	if len(res.Fields) == 0 && len(res.GUIDsAdded) == 0 && len(res.GUIDsRemoved) == 0 {
+		return nil
+	}
+
+	return res
+}
+
+// describeMember describes the membership and index0 of the member, e.g. "limited,index0".
+func describeMember(m GUIDMember) string {
+	if m.Index0 {
+		return string(m.Membership) + ",index0"
+	}
+	return string(m.Membership)
+}
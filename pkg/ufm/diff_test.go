@@ -0,0 +1,77 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufm_test
+
+import (
+	"testing"
+
+	"github.com/openbce/kperf/pkg/ufm"
+)
+
+func TestDiffIBNetworks(t *testing.T) {
+	from := []*ufm.IBNetwork{
+		{Name: "tenant-a", PKey: 0x100, GUIDs: []string{guid1}, MTU: 2},
+		{Name: "tenant-b", PKey: 0x200},
+		{Name: "tenant-c", PKey: 0x300, MTU: 2},
+	}
+	to := []*ufm.IBNetwork{
+		{Name: "tenant-a", PKey: 0x100, GUIDs: []string{guid2}, MTU: 4},
+		{PKey: 0x300, MTU: 2},
+		{Name: "tenant-d", PKey: 0x400, GUIDs: []string{guid3}},
+	}
+
+	res := ufm.DiffIBNetworks(from, to)
+	if len(res) != 3 {
+		t.Fatalf("got %d diffs, expected 3: %+v", len(res), res)
+	}
+
+	if d := res[0]; d.PKey != 0x100 || d.Type != ufm.DiffModified || len(d.Fields) != 1 || d.Fields[0].Field != "mtu" ||
+		len(d.GUIDsAdded) != 1 || len(d.GUIDsRemoved) != 1 {
+		t.Errorf("unexpected diff of 0x100: %+v", d)
+	}
+	if d := res[1]; d.PKey != 0x200 || d.Type != ufm.DiffRemoved {
+		t.Errorf("unexpected diff of 0x200: %+v", d)
+	}
+	// The name of 0x300 is not set in the target, so it's unchanged.
+	if d := res[2]; d.PKey != 0x400 || d.Type != ufm.DiffAdded {
+		t.Errorf("unexpected diff of 0x400: %+v", d)
+	}
+}
+
+func TestDiffUnsetName(t *testing.T) {
+	tests := []struct {
+		name  string
+		from  string
+		to    string
+		drift bool
+	}{
+		{name: "unset in from", from: "", to: "tenant-a"},
+		{name: "unset in to", from: "tenant-a", to: ""},
+		{name: "same", from: "tenant-a", to: "tenant-a"},
+		{name: "changed", from: "tenant-a", to: "tenant-b", drift: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from := []*ufm.IBNetwork{{Name: tt.from, PKey: 0x100, MTU: 2}}
+			to := []*ufm.IBNetwork{{Name: tt.to, PKey: 0x100, MTU: 2}}
+			res := ufm.DiffIBNetworks(from, to)
+			if drift := len(res) != 0; drift != tt.drift {
+				t.Errorf("got diffs %+v, expected drift %t", res, tt.drift)
+			}
+		})
+	}
+}
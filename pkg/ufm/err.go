@@ -16,24 +16,167 @@ limitations under the License.
 
 package ufm
 
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
 type ErrCode int32
 
 const (
-	UnknownErr     ErrCode = -1
-	NotFoundErr    ErrCode = 1
-	InvalidPKeyErr ErrCode = 2
-	AuthErr        ErrCode = 3
+	UnknownErr       ErrCode = -1
+	NotFoundErr      ErrCode = 1
+	InvalidPKeyErr   ErrCode = 2
+	AuthErr          ErrCode = 3
+	UnauthorizedErr  ErrCode = 4
+	ForbiddenErr     ErrCode = 5
+	ConflictErr      ErrCode = 6
+	BadRequestErr    ErrCode = 7
+	RateLimitedErr   ErrCode = 8
+	ServerErr        ErrCode = 9
+	TimeoutErr       ErrCode = 10
+	InvalidConfigErr ErrCode = 11
+)
+
+// The sentinel errors of each ErrCode, e.g. errors.Is(err, ufm.ErrNotFound).
+var (
+	ErrUnknown       = &UFMError{Code: UnknownErr, Message: "unknown error"}
+	ErrNotFound      = &UFMError{Code: NotFoundErr, Message: "not found"}
+	ErrInvalidPKey   = &UFMError{Code: InvalidPKeyErr, Message: "invalid pkey"}
+	ErrAuth          = &UFMError{Code: AuthErr, Message: "invalid authentication"}
+	ErrUnauthorized  = &UFMError{Code: UnauthorizedErr, Message: "unauthorized"}
+	ErrForbidden     = &UFMError{Code: ForbiddenErr, Message: "forbidden"}
+	ErrConflict      = &UFMError{Code: ConflictErr, Message: "conflict"}
+	ErrBadRequest    = &UFMError{Code: BadRequestErr, Message: "bad request"}
+	ErrRateLimited   = &UFMError{Code: RateLimitedErr, Message: "rate limited"}
+	ErrServer        = &UFMError{Code: ServerErr, Message: "server error"}
+	ErrTimeout       = &UFMError{Code: TimeoutErr, Message: "timeout"}
+	ErrInvalidConfig = &UFMError{Code: InvalidConfigErr, Message: "invalid config"}
 )
 
 type UFMError struct {
 	Code    ErrCode
 	Message string
+	// The HTTP status code of the response from UFM; 0 if there's no response.
+	StatusCode int
+	// The error payload in the response from UFM.
+	Payload []byte
+	// The attempts of the request to UFM, including retries.
+	Attempts int
+	// The underlying error, e.g. the error of the transport.
+	Err error
 }
 
 func (u *UFMError) Error() string {
+	if u.Attempts > 1 {
+		return fmt.Sprintf("%s (after %d attempts)", u.Message, u.Attempts)
+	}
 	return u.Message
 }
 
+// Unwrap returns the underlying error, so errors.Is(err, context.DeadlineExceeded) works.
+func (u *UFMError) Unwrap() error {
+	return u.Err
+}
+
+// Is reports whether target is a UFMError with the same code, e.g. one of the sentinel errors.
+func (u *UFMError) Is(target error) bool {
+	t, ok := target.(*UFMError)
+	if !ok {
+		return false
+	}
+	return u.Code == t.Code
+}
+
 func (u *UFMError) IsNotFound() bool {
 	return u.Code == NotFoundErr
 }
+
+// wrapError returns a copy of err whose message is prefixed by the given one; the
+// code and the details of the response are kept.
+func wrapError(err *UFMError, format string, args ...interface{}) *UFMError {
+	res := *err
+	res.Message = fmt.Sprintf("%s with error: %s", fmt.Sprintf(format, args...), err.Message)
+	return &res
+}
+
+// newTransportError builds the UFMError of a request which got no response from UFM.
+func newTransportError(err error) *UFMError {
+	code := UnknownErr
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		code = TimeoutErr
+	}
+
+	return &UFMError{
+		Code:    code,
+		Message: err.Error(),
+		Err:     err,
+	}
+}
+
+// newHTTPError builds the UFMError of a failed response according to its status code and payload.
+func newHTTPError(statusCode int, payload []byte) *UFMError {
+	code := UnknownErr
+	switch {
+	case statusCode == http.StatusBadRequest:
+		code = BadRequestErr
+	case statusCode == http.StatusUnauthorized:
+		code = UnauthorizedErr
+	case statusCode == http.StatusForbidden:
+		code = ForbiddenErr
+	case statusCode == http.StatusNotFound:
+		code = NotFoundErr
+	case statusCode == http.StatusConflict:
+		code = ConflictErr
+	case statusCode == http.StatusTooManyRequests:
+		code = RateLimitedErr
+	case statusCode == http.StatusRequestTimeout, statusCode == http.StatusGatewayTimeout:
+		code = TimeoutErr
+	case statusCode >= http.StatusInternalServerError:
+		code = ServerErr
+	}
+
+	msg := fmt.Sprintf("http status (%d): %s", statusCode, http.StatusText(statusCode))
+	if reason := parseErrorPayload(payload); reason != "" {
+		msg = fmt.Sprintf("%s: %s", msg, reason)
+	}
+
+	return &UFMError{
+		Code:       code,
+		Message:    msg,
+		StatusCode: statusCode,
+		Payload:    payload,
+	}
+}
+
+// parseErrorPayload returns the reason in the error payload of UFM, which is either
+// a JSON object, e.g. {"error": "..."}, or a plain text.
+func parseErrorPayload(payload []byte) string {
+	text := strings.TrimSpace(string(payload))
+	if text == "" {
+		return ""
+	}
+
+	obj := map[string]interface{}{}
+	if err := json.Unmarshal(payload, &obj); err != nil {
+		if strings.HasPrefix(text, "<") {
+			// Ignore the HTML pages, e.g. from the proxy in front of UFM.
+			return ""
+		}
+		return text
+	}
+
+	for _, key := range []string{"error", "message", "error_message", "description", "detail"} {
+		if v, found := obj[key]; found {
+			return fmt.Sprint(v)
+		}
+	}
+
+	return text
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufm_test
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/openbce/kperf/pkg/ufm"
+	"github.com/openbce/kperf/pkg/ufm/ufmtest"
+)
+
+var sentinels = []*ufm.UFMError{
+	ufm.ErrUnknown, ufm.ErrNotFound, ufm.ErrInvalidPKey, ufm.ErrAuth, ufm.ErrUnauthorized, ufm.ErrForbidden,
+	ufm.ErrConflict, ufm.ErrBadRequest, ufm.ErrRateLimited, ufm.ErrServer, ufm.ErrTimeout, ufm.ErrInvalidConfig,
+}
+
+func TestHTTPErrorCode(t *testing.T) {
+	tests := []struct {
+		statusCode int
+		want       *ufm.UFMError
+	}{
+		{statusCode: http.StatusBadRequest, want: ufm.ErrBadRequest},
+		{statusCode: http.StatusUnauthorized, want: ufm.ErrUnauthorized},
+		{statusCode: http.StatusForbidden, want: ufm.ErrForbidden},
+		{statusCode: http.StatusNotFound, want: ufm.ErrNotFound},
+		{statusCode: http.StatusConflict, want: ufm.ErrConflict},
+		{statusCode: http.StatusTooManyRequests, want: ufm.ErrRateLimited},
+		{statusCode: http.StatusRequestTimeout, want: ufm.ErrTimeout},
+		{statusCode: http.StatusInternalServerError, want: ufm.ErrServer},
+		{statusCode: http.StatusServiceUnavailable, want: ufm.ErrServer},
+		{statusCode: http.StatusGatewayTimeout, want: ufm.ErrTimeout},
+		{statusCode: http.StatusTeapot, want: ufm.ErrUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.statusCode), func(t *testing.T) {
+			u, srv := newUFM(t, "")
+			srv.AddIBNetwork(&ufm.IBNetwork{PKey: 0x100, GUIDs: []string{guid1}})
+			srv.AddFault(ufmtest.Fault{Method: http.MethodGet, Path: "/ufmRest/resources/pkeys/0x100", StatusCode: tt.statusCode})
+
+			_, ufmErr := u.GetIBNetwork(0x100)
+			if ufmErr == nil {
+				t.Fatalf("expected error of http status %d", tt.statusCode)
+			}
+			if ufmErr.Code != tt.want.Code || ufmErr.StatusCode != tt.statusCode {
+				t.Errorf("got code %d and status %d, expected code %d and status %d", ufmErr.Code, ufmErr.StatusCode, tt.want.Code, tt.statusCode)
+			}
+
+			var err error = ufmErr
+			for _, s := range sentinels {
+				if got := errors.Is(err, s); got != (s == tt.want) {
+					t.Errorf("errors.Is(err, %q) is %v", s.Message, got)
+				}
+			}
+		})
+	}
+}
+
+func TestWrappedError(t *testing.T) {
+	u, srv := newUFM(t, "")
+	srv.AddIBNetwork(&ufm.IBNetwork{PKey: 0x100, GUIDs: []string{guid1}})
+	srv.AddFault(ufmtest.Fault{Method: http.MethodGet, Path: "/ufmRest/resources/pkeys/0x100", StatusCode: http.StatusNotFound, Body: `{"error": "pkey is gone"}`})
+
+	_, ufmErr := u.GetIBNetwork(0x100)
+	if ufmErr == nil {
+		t.Fatalf("expected error of not found")
+	}
+
+	var err error = ufmErr
+	if !errors.Is(err, ufm.ErrNotFound) {
+		t.Errorf("the wrapped error %v is not ErrNotFound", err)
+	}
+
+	var target *ufm.UFMError
+	if !errors.As(err, &target) {
+		t.Fatalf("the wrapped error %v is not UFMError", err)
+	}
+	if target.StatusCode != http.StatusNotFound || string(target.Payload) != `{"error": "pkey is gone"}` {
+		t.Errorf("the details of the response are lost: %+v", target)
+	}
+	if !strings.HasPrefix(target.Message, "failed to get pkey 0x0100") || !strings.HasSuffix(target.Message, "pkey is gone") {
+		t.Errorf("unexpected message %q", target.Message)
+	}
+}
+
+func TestErrorPayload(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "error", body: `{"error": "bad pkey"}`, want: "http status (400): Bad Request: bad pkey"},
+		{name: "message", body: `{"message": "bad pkey"}`, want: "http status (400): Bad Request: bad pkey"},
+		{name: "error_message", body: `{"error_message": "bad pkey"}`, want: "http status (400): Bad Request: bad pkey"},
+		{name: "description", body: `{"description": "bad pkey"}`, want: "http status (400): Bad Request: bad pkey"},
+		{name: "detail", body: `{"detail": "bad pkey"}`, want: "http status (400): Bad Request: bad pkey"},
+		{name: "plain text", body: "bad pkey\n", want: "http status (400): Bad Request: bad pkey"},
+		{name: "html", body: "<html><body>Bad Request</body></html>", want: "http status (400): Bad Request"},
+		{name: "empty", want: "http status (400): Bad Request"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, srv := newUFM(t, "")
+			srv.AddFault(ufmtest.Fault{Method: http.MethodGet, Path: "/ufmRest/app/ufm_version", StatusCode: http.StatusBadRequest, Body: tt.body})
+
+			_, ufmErr := u.Version()
+			if ufmErr == nil {
+				t.Fatalf("expected error of bad request")
+			}
+			if !strings.HasSuffix(ufmErr.Message, tt.want) {
+				t.Errorf("message is %q, expected the suffix %q", ufmErr.Message, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	eventsPath = "/ufmRest/app/events"
+	alarmsPath = "/ufmRest/app/alarms"
+)
+
+// Severity is the severity of the events and alarms in UFM.
+type Severity string
+
+const (
+	InfoSeverity     Severity = "Info"
+	WarningSeverity  Severity = "Warning"
+	MinorSeverity    Severity = "Minor"
+	CriticalSeverity Severity = "Critical"
+)
+
+var severities = []Severity{InfoSeverity, WarningSeverity, MinorSeverity, CriticalSeverity}
+
+// ParseSeverity parses the severity case insensitively; empty means all the severities.
+func ParseSeverity(s string) (Severity, error) {
+	if s == "" {
+		return "", nil
+	}
+	for _, sev := range severities {
+		if strings.EqualFold(s, string(sev)) {
+			return sev, nil
+		}
+	}
+
+	return "", fmt.Errorf("invalid severity %q, one of 'Info', 'Warning', 'Minor' or 'Critical'", s)
+}
+
+// level returns the order of the severity, e.g. Critical is higher than Warning; the unknown
+// severities are the lowest.
+func (s Severity) level() int {
+	for i, sev := range severities {
+		if strings.EqualFold(string(s), string(sev)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// timeLayouts are the layouts of the timestamps in UFM, which are in UTC if without zone.
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05.000", "2006-01-02 15:04:05"}
+
+func parseTimestamp(s string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", s)
+}
+
+// IBEvent is an entry of the event log of UFM, e.g. a port state change or a failed request.
+type IBEvent struct {
+	ID       int64    `json:"id"`
+	Name     string   `json:"name"`
+	Severity Severity `json:"severity"`
+	// The time of the event, e.g. "2023-05-24 10:01:02".
+	Timestamp string `json:"timestamp"`
+	// The category of the event, e.g. Hardware or Fabric Topology.
+	Category string `json:"category"`
+	// The object of the event, e.g. the port GUID or the system name.
+	ObjectName string `json:"object_name"`
+	ObjectPath string `json:"object_path"`
+	// The type of the object, e.g. Port, Switch or Computer.
+	ObjectType  string `json:"type"`
+	Description string `json:"description"`
+	Counter     int64  `json:"counter"`
+}
+
+// Time returns the parsed Timestamp of the event.
+func (e *IBEvent) Time() (time.Time, error) {
+	return parseTimestamp(e.Timestamp)
+}
+
+// IBAlarm is an active alarm in UFM, which is raised by the events of an object.
+type IBAlarm struct {
+	ID       int64    `json:"id"`
+	Name     string   `json:"name"`
+	Severity Severity `json:"severity"`
+	// The time of the last event of the alarm, e.g. "2023-05-24 10:01:02".
+	Timestamp  string `json:"timestamp"`
+	ObjectName string `json:"object_name"`
+	ObjectType string `json:"object_type"`
+	Reason     string `json:"reason"`
+	// The description of the alarm; the category of the events is not kept in the alarms.
+	Description string `json:"description"`
+	Counter     int64  `json:"counter"`
+}
+
+// Time returns the parsed Timestamp of the alarm.
+func (a *IBAlarm) Time() (time.Time, error) {
+	return parseTimestamp(a.Timestamp)
+}
+
+// EventQuery selects the events and alarms; the empty options match all of them. The options
+// are filtered locally, as UFM returns all the recent events.
+type EventQuery struct {
+	// The lowest severity, e.g. Warning matches Warning, Minor and Critical.
+	MinSeverity Severity
+	// The time window; the zero values are unlimited. The entries with an invalid timestamp
+	// are kept.
+	Since time.Time
+	Until time.Time
+	// The object, e.g. a port GUID or a system name; matched case insensitively against the
+	// object name and path.
+	Object string
+	// The category of the events; it's ignored for the alarms.
+	Category string
+}
+
+func (q *EventQuery) matchTime(ts string) bool {
+	if q.Since.IsZero() && q.Until.IsZero() {
+		return true
+	}
+	t, err := parseTimestamp(ts)
+	if err != nil {
+		return true
+	}
+
+	return !t.Before(q.Since) && (q.Until.IsZero() || !t.After(q.Until))
+}
+
+func (q *EventQuery) matchObject(fields ...string) bool {
+	if q.Object == "" {
+		return true
+	}
+	object := strings.ToLower(normalizeGUID(q.Object))
+	for _, f := range fields {
+		if strings.Contains(strings.ToLower(f), object) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (q *EventQuery) matchSeverity(s Severity) bool {
+	return q.MinSeverity == "" || s.level() >= q.MinSeverity.level()
+}
+
+func (q *EventQuery) matchEvent(e *IBEvent) bool {
+	return q.matchSeverity(e.Severity) && q.matchTime(e.Timestamp) &&
+		q.matchObject(e.ObjectName, e.ObjectPath) &&
+		(q.Category == "" || strings.EqualFold(q.Category, e.Category))
+}
+
+func (q *EventQuery) matchAlarm(a *IBAlarm) bool {
+	return q.matchSeverity(a.Severity) && q.matchTime(a.Timestamp) && q.matchObject(a.ObjectName)
+}
+
+func (u *UFM) ListEvents(query *EventQuery) ([]*IBEvent, *UFMError) {
+	return u.ListEventsWithContext(context.Background(), query)
+}
+
+// ListEventsWithContext lists the events matching the query in UFM.
+func (u *UFM) ListEventsWithContext(ctx context.Context, query *EventQuery) ([]*IBEvent, *UFMError) {
+	if query == nil {
+		query = &EventQuery{}
+	}
+
+	data, err := u.client.GetWithContext(ctx, u.buildURL(eventsPath))
+	if err != nil {
+		return nil, wrapError(err, "failed to list events")
+	}
+
+	events := []*IBEvent{}
+	if err := json.Unmarshal(data, &events); err != nil {
+		return nil, &UFMError{
+			Code:    UnknownErr,
+			Message: fmt.Sprintf("failed to unmarshal events with error: %v", err),
+			Err:     err,
+		}
+	}
+
+	res := []*IBEvent{}
+	for _, e := range events {
+		if query.matchEvent(e) {
+			res = append(res, e)
+		}
+	}
+
+	return res, nil
+}
+
+func (u *UFM) ListAlarms(query *EventQuery) ([]*IBAlarm, *UFMError) {
+	return u.ListAlarmsWithContext(context.Background(), query)
+}
+
+// ListAlarmsWithContext lists the alarms matching the query in UFM.
+func (u *UFM) ListAlarmsWithContext(ctx context.Context, query *EventQuery) ([]*IBAlarm, *UFMError) {
+	if query == nil {
+		query = &EventQuery{}
+	}
+
+	data, err := u.client.GetWithContext(ctx, u.buildURL(alarmsPath))
+	if err != nil {
+		return nil, wrapError(err, "failed to list alarms")
+	}
+
+	alarms := []*IBAlarm{}
+	if err := json.Unmarshal(data, &alarms); err != nil {
+		return nil, &UFMError{
+			Code:    UnknownErr,
+			Message: fmt.Sprintf("failed to unmarshal alarms with error: %v", err),
+			Err:     err,
+		}
+	}
+
+	res := []*IBAlarm{}
+	for _, a := range alarms {
+		if query.matchAlarm(a) {
+			res = append(res, a)
+		}
+	}
+
+	return res, nil
+}
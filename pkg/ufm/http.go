@@ -18,14 +18,14 @@ package ufm
 
 import (
 	"bytes"
-	"crypto/tls"
-	"crypto/x509"
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"time"
 
-	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog"
 )
 
 type UFMClient interface {
@@ -33,63 +33,131 @@ type UFMClient interface {
 	Post(url string, body []byte) ([]byte, *UFMError)
 	Put(url string, body []byte) ([]byte, *UFMError)
 	Delete(url string) ([]byte, *UFMError)
+
+	GetWithContext(ctx context.Context, url string) ([]byte, *UFMError)
+	PostWithContext(ctx context.Context, url string, body []byte) ([]byte, *UFMError)
+	PutWithContext(ctx context.Context, url string, body []byte) ([]byte, *UFMError)
+	DeleteWithContext(ctx context.Context, url string) ([]byte, *UFMError)
+
+	// DoWithContext sends the request, and returns the whole response, e.g. for the Location
+	// header of an accepted request.
+	DoWithContext(ctx context.Context, method, url string, body []byte) (*Response, *UFMError)
 }
 
-type BasicAuth struct {
-	Username string
-	Password string
+// Response is a succeeded response of UFM.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
 }
 
 type ufmclient struct {
-	basicAuth  *BasicAuth
-	httpClient *http.Client
+	auth        Authenticator
+	httpClient  *http.Client
+	retryPolicy RetryPolicy
+	logger      zerolog.Logger
+	userAgent   string
 }
 
-func NewClient(isSecure bool, basicAuth *BasicAuth, cert string) (UFMClient, *UFMError) {
-	log.Debug().Msgf("creating http ufmclient, isSecure %v, basicAuth %+v, cert %s", isSecure, basicAuth, cert)
-	if basicAuth == nil {
+func NewClient(isSecure bool, auth Authenticator, tlsConf *TLSConfig, opts ...Option) (UFMClient, *UFMError) {
+	o := newOptions(opts...)
+	o.logger.Debug().Msgf("creating http ufmclient, isSecure %v, auth %v, tlsConf %+v", isSecure, auth, tlsConf)
+	if auth == nil {
 		return nil, &UFMError{
 			Code:    AuthErr,
-			Message: fmt.Sprintf("invalid basicAuth value %v", basicAuth),
+			Message: fmt.Sprintf("invalid auth value %v", auth),
 		}
 	}
-	httpClient := &http.Client{Transport: http.DefaultTransport}
+
+	if l, ok := auth.(loggerSetter); ok {
+		l.setLogger(o.logger)
+	}
+
+	c := &ufmclient{
+		auth:        auth,
+		httpClient:  o.httpClient,
+		retryPolicy: DefaultRetryPolicy(),
+		logger:      o.logger,
+		userAgent:   o.userAgent,
+	}
+	if o.retryPolicy != nil {
+		c.retryPolicy = *o.retryPolicy
+	}
+	if c.httpClient != nil {
+		return c, nil
+	}
+
+	// Clone the default transport for each ufmclient, so the TLS config will not impact others.
+	transport := http.DefaultTransport.(*http.Transport).Clone()
 	if isSecure {
-		if cert == "" {
-			/* #nosec */
-			httpClient.Transport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-		} else {
-			caCertPool := x509.NewCertPool()
-			caCertPool.AppendCertsFromPEM([]byte(cert))
-			httpClient.Transport.(*http.Transport).TLSClientConfig = &tls.Config{RootCAs: caCertPool}
+		if tlsConf == nil {
+			tlsConf = &TLSConfig{}
+		}
+		tlsClientConfig, err := tlsConf.build()
+		if err != nil {
+			return nil, &UFMError{
+				Code:    InvalidConfigErr,
+				Message: fmt.Sprintf("invalid TLS config: %v", err),
+				Err:     err,
+			}
 		}
+		transport.TLSClientConfig = tlsClientConfig
 	}
+	c.httpClient = &http.Client{Transport: transport}
 
-	return &ufmclient{basicAuth: basicAuth, httpClient: httpClient}, nil
+	return c, nil
 }
 
 func (c *ufmclient) Get(url string) ([]byte, *UFMError) {
-	log.Debug().Msgf("Http ufmclient GET: url %s", url)
-	return c.executeRequest(http.MethodGet, url, nil)
+	return c.GetWithContext(context.Background(), url)
 }
 
 func (c *ufmclient) Post(url string, body []byte) ([]byte, *UFMError) {
-	log.Debug().Msgf("Http ufmclient POST: url %s,  body %s", url, string(body))
-	return c.executeRequest(http.MethodPost, url, body)
+	return c.PostWithContext(context.Background(), url, body)
 }
 
 func (c *ufmclient) Put(url string, body []byte) ([]byte, *UFMError) {
-	log.Debug().Msgf("Http ufmclient PUT: url %s,  body %s", url, string(body))
-	return c.executeRequest(http.MethodPut, url, body)
+	return c.PutWithContext(context.Background(), url, body)
 }
 
 func (c *ufmclient) Delete(url string) ([]byte, *UFMError) {
-	log.Debug().Msgf("Http ufmclient DELETE: url %s", url)
-	return c.executeRequest(http.MethodDelete, url, nil)
+	return c.DeleteWithContext(context.Background(), url)
+}
+
+func (c *ufmclient) GetWithContext(ctx context.Context, url string) ([]byte, *UFMError) {
+	return responseBody(c.DoWithContext(ctx, http.MethodGet, url, nil))
+}
+
+func (c *ufmclient) PostWithContext(ctx context.Context, url string, body []byte) ([]byte, *UFMError) {
+	return responseBody(c.DoWithContext(ctx, http.MethodPost, url, body))
+}
+
+func (c *ufmclient) PutWithContext(ctx context.Context, url string, body []byte) ([]byte, *UFMError) {
+	return responseBody(c.DoWithContext(ctx, http.MethodPut, url, body))
 }
 
-func (c *ufmclient) createRequest(method, url string, body io.Reader) (*http.Request, *UFMError) {
-	req, err := http.NewRequest(method, url, body)
+func (c *ufmclient) DeleteWithContext(ctx context.Context, url string) ([]byte, *UFMError) {
+	return responseBody(c.DoWithContext(ctx, http.MethodDelete, url, nil))
+}
+
+func (c *ufmclient) DoWithContext(ctx context.Context, method, url string, body []byte) (*Response, *UFMError) {
+	if body == nil {
+		c.logger.Debug().Msgf("Http ufmclient %s: url %s", method, url)
+	} else {
+		c.logger.Debug().Msgf("Http ufmclient %s: url %s,  body %s", method, url, string(body))
+	}
+	return c.executeRequest(ctx, method, url, body)
+}
+
+func responseBody(resp *Response, ufmErr *UFMError) ([]byte, *UFMError) {
+	if ufmErr != nil {
+		return nil, ufmErr
+	}
+	return resp.Body, nil
+}
+
+func (c *ufmclient) createRequest(ctx context.Context, method, url string, body io.Reader) (*http.Request, *UFMError) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, &UFMError{
 			Code:    UnknownErr,
@@ -97,39 +165,92 @@ func (c *ufmclient) createRequest(method, url string, body io.Reader) (*http.Req
 		}
 	}
 
-	req.SetBasicAuth(c.basicAuth.Username, c.basicAuth.Password)
+	if err := c.auth.Authenticate(ctx, c.httpClient, req); err != nil {
+		return nil, &UFMError{
+			Code:    AuthErr,
+			Message: fmt.Sprintf("failed to authenticate request: %v", err),
+			Err:     err,
+		}
+	}
 
 	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
 	return req, nil
 }
 
-func (c *ufmclient) executeRequest(method, url string, body []byte) ([]byte, *UFMError) {
-	req, ufmErr := c.createRequest(method, url, bytes.NewBuffer(body))
+func (c *ufmclient) executeRequest(ctx context.Context, method, url string, body []byte) (*Response, *UFMError) {
+	attempts := c.retryPolicy.attempts(ctx, method)
+
+	for attempt := 1; ; attempt++ {
+		resp, retryAfter, retryable, ufmErr := c.doRequest(ctx, method, url, body)
+		if ufmErr == nil {
+			return resp, nil
+		}
+		ufmErr.Attempts = attempt
+
+		if !retryable || attempt >= attempts || ctx.Err() != nil {
+			return nil, ufmErr
+		}
+
+		backoff := c.retryPolicy.backoff(attempt)
+		if retryAfter > backoff {
+			backoff = retryAfter
+		}
+		c.logger.Debug().Msgf("Http ufmclient %s: url %s, attempt %d/%d failed: %v, retry in %v",
+			method, url, attempt, attempts, ufmErr, backoff)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ufmErr
+		case <-timer.C:
+		}
+	}
+}
+
+// doRequest sends the request once; it returns the response if succeeded, otherwise the
+// error, whether it's retryable and the delay required by the UFM (Retry-After).
+func (c *ufmclient) doRequest(ctx context.Context, method, url string, body []byte) (*Response, time.Duration, bool, *UFMError) {
+	resp, responseBody, err := c.send(ctx, method, url, body)
+
+	// The credentials may expire, e.g. session timeout; invalidate them and send the request again.
+	if i, ok := c.auth.(invalidator); ok && err == nil && resp.StatusCode == http.StatusUnauthorized {
+		c.logger.Debug().Msgf("Http ufmclient %s: url %s, unauthorized, re-authenticating", method, url)
+		i.Invalidate()
+		resp, responseBody, err = c.send(ctx, method, url, body)
+	}
+
+	if err != nil {
+		if ufmErr, ok := err.(*UFMError); ok {
+			return nil, 0, false, ufmErr
+		}
+		return nil, 0, c.retryPolicy.isRetryableError(err), newTransportError(err)
+	}
+
+	// The asynchronous requests are accepted by 202, e.g. to start a monitoring session.
+	if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+		return &Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: responseBody}, 0, false, nil
+	}
+
+	return nil, parseRetryAfter(resp.Header), c.retryPolicy.isRetryableStatus(resp.StatusCode),
+		newHTTPError(resp.StatusCode, responseBody)
+}
+
+// send sends the request and reads the whole response body.
+func (c *ufmclient) send(ctx context.Context, method, url string, body []byte) (*http.Response, []byte, error) {
+	req, ufmErr := c.createRequest(ctx, method, url, bytes.NewBuffer(body))
 	if ufmErr != nil {
-		return nil, ufmErr
+		return nil, nil, ufmErr
 	}
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, &UFMError{
-			Code:    UnknownErr,
-			Message: err.Error(),
-		}
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
 	responseBody, _ := ioutil.ReadAll(resp.Body)
-	switch resp.StatusCode {
-	case http.StatusOK:
-		return responseBody, nil
-	case http.StatusNotFound:
-		return nil, &UFMError{
-			Code:    NotFoundErr,
-			Message: http.StatusText(http.StatusNotFound),
-		}
-	}
 
-	return nil, &UFMError{
-		Code: UnknownErr,
-		Message: fmt.Sprintf("http status (%d): %s",
-			resp.StatusCode, http.StatusText(resp.StatusCode)),
-	}
+	return resp, responseBody, nil
 }
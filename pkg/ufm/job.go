@@ -0,0 +1,250 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	jobPath = "/ufmRest/jobs/%s"
+
+	// The backoff of polling a job, which grows from jobPollInitialInterval to jobPollMaxInterval.
+	jobPollInitialInterval = 500 * time.Millisecond
+	jobPollMaxInterval     = 10 * time.Second
+	jobPollMultiplier      = 2
+)
+
+// JobStatus is the status of a job in UFM.
+type JobStatus string
+
+const (
+	// JobAccepted is the status of a job accepted by UFM but not polled yet, e.g. WithWait(false).
+	JobAccepted            JobStatus = "Accepted"
+	JobRunning             JobStatus = "Running"
+	JobCompleted           JobStatus = "Completed"
+	JobCompletedWithErrors JobStatus = "Completed With Errors"
+	JobFailed              JobStatus = "Failed"
+	JobStopped             JobStatus = "Stopped"
+	JobAborted             JobStatus = "Aborted"
+)
+
+// Job is a long-running action in UFM, e.g. a port reset or a large update of the GUIDs of
+// a pkey, which is accepted by UFM before it finishes.
+type Job struct {
+	ID          string    `json:"ID"`
+	Status      JobStatus `json:"Status"`
+	Progress    int32     `json:"Progress"`
+	Operation   string    `json:"Operation,omitempty"`
+	Description string    `json:"Description,omitempty"`
+	Summary     string    `json:"Summary,omitempty"`
+	Created     string    `json:"Created,omitempty"`
+	LastUpdated string    `json:"LastUpdated,omitempty"`
+}
+
+// IsDone returns true if the job finished, succeeded or not.
+func (j *Job) IsDone() bool {
+	switch j.Status {
+	case JobCompleted, JobCompletedWithErrors, JobFailed, JobStopped, JobAborted:
+		return true
+	}
+	return false
+}
+
+// Err returns the error of the finished job, or nil if it completed or is not done.
+func (j *Job) Err() *UFMError {
+	if !j.IsDone() || j.Status == JobCompleted {
+		return nil
+	}
+
+	return &UFMError{
+		Code:    ServerErr,
+		Message: fmt.Sprintf("job %s %s: %s", j.ID, strings.ToLower(string(j.Status)), j.Summary),
+		Err:     &JobError{Job: j},
+	}
+}
+
+// JobError is the Err of the UFMError of a failed job; the changes of the job may be partly
+// done, e.g. "Completed With Errors".
+type JobError struct {
+	Job *Job
+}
+
+func (e *JobError) Error() string {
+	return fmt.Sprintf("job %s %s: %s", e.Job.ID, strings.ToLower(string(e.Job.Status)), e.Job.Summary)
+}
+
+// JobProgressFunc is called with the status of a job when it's accepted and changed.
+type JobProgressFunc func(job *Job)
+
+// JobOption controls how a mutating method, e.g. CreateIBNetworkWithContext, handles its jobs
+// in UFM.
+type JobOption func(*jobOptions)
+
+type jobOptions struct {
+	noWait   bool
+	progress JobProgressFunc
+}
+
+// WithWait sets whether the mutating method waits for its jobs to finish, which is true by
+// default; otherwise it returns once UFM accepts the jobs.
+func WithWait(wait bool) JobOption {
+	return func(o *jobOptions) {
+		o.noWait = !wait
+	}
+}
+
+// WithJobProgress reports the jobs of the mutating method to fn.
+func WithJobProgress(fn JobProgressFunc) JobOption {
+	return func(o *jobOptions) {
+		o.progress = fn
+	}
+}
+
+func newJobOptions(opts ...JobOption) *jobOptions {
+	o := &jobOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// waited returns a copy of the options which waits for the jobs, e.g. for the requests whose
+// results are checked at once.
+func (o *jobOptions) waited() *jobOptions {
+	res := *o
+	res.noWait = false
+	return &res
+}
+
+func (u *UFM) GetJob(id string) (*Job, *UFMError) {
+	return u.GetJobWithContext(context.Background(), id)
+}
+
+// GetJobWithContext gets the current status of the job.
+func (u *UFM) GetJobWithContext(ctx context.Context, id string) (*Job, *UFMError) {
+	data, ufmErr := u.client.GetWithContext(ctx, u.buildURL(fmt.Sprintf(jobPath, id)))
+	if ufmErr != nil {
+		return nil, wrapError(ufmErr, "failed to get job %s", id)
+	}
+
+	job := &Job{}
+	if err := json.Unmarshal(data, job); err != nil {
+		return nil, &UFMError{
+			Code:    UnknownErr,
+			Message: fmt.Sprintf("failed to unmarshal job %s with error: %v", id, err),
+			Err:     err,
+		}
+	}
+
+	return job, nil
+}
+
+func (u *UFM) WaitForJob(id string, progress JobProgressFunc) (*Job, *UFMError) {
+	return u.WaitForJobWithContext(context.Background(), id, progress)
+}
+
+// WaitForJobWithContext polls the job with backoff until it finishes, and reports its status to
+// progress, if any, when changed; the last status of the job, JobAccepted if never got, is
+// returned with the error if it failed or the context is done.
+func (u *UFM) WaitForJobWithContext(ctx context.Context, id string, progress JobProgressFunc) (*Job, *UFMError) {
+	last := &Job{ID: id, Status: JobAccepted}
+	interval := jobPollInitialInterval
+	for {
+		job, ufmErr := u.GetJobWithContext(ctx, id)
+		if ufmErr != nil {
+			return last, ufmErr
+		}
+		if progress != nil && (last.Status != job.Status || last.Progress != job.Progress) {
+			progress(job)
+		}
+		last = job
+
+		if job.IsDone() {
+			return job, job.Err()
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return job, newTransportError(ctx.Err())
+		case <-timer.C:
+		}
+
+		if interval *= jobPollMultiplier; interval > jobPollMaxInterval {
+			interval = jobPollMaxInterval
+		}
+	}
+}
+
+// parseJobID returns the ID of the job of an accepted mutating request, if any; UFM refers to
+// the job by {"job_id": 1}, by {"id": 1} for the actions, or by its path in the body or in the
+// Location header of 202, e.g. /ufmRest/jobs/1.
+func parseJobID(resp *Response) (string, bool) {
+	ref := struct {
+		ID       json.Number `json:"id"`
+		JobID    json.Number `json:"job_id"`
+		Location string      `json:"location"`
+	}{}
+	if len(bytes.TrimSpace(resp.Body)) != 0 {
+		if err := json.Unmarshal(resp.Body, &ref); err != nil {
+			return "", false
+		}
+	}
+	if ref.JobID != "" {
+		return ref.JobID.String(), true
+	}
+	if ref.ID != "" {
+		return ref.ID.String(), true
+	}
+	if ref.Location == "" && resp.StatusCode == http.StatusAccepted {
+		ref.Location = resp.Header.Get("Location")
+	}
+	if i := strings.LastIndex(ref.Location, "/jobs/"); i >= 0 && len(ref.Location) > i+len("/jobs/") {
+		return ref.Location[i+len("/jobs/"):], true
+	}
+
+	return "", false
+}
+
+// trackJob tracks the job in the response of a mutating request: it waits for the job to
+// finish unless WithWait(false), and reports it to WithJobProgress. The job is nil if the
+// request finished synchronously.
+func (u *UFM) trackJob(ctx context.Context, resp *Response, jo *jobOptions) (*Job, *UFMError) {
+	id, found := parseJobID(resp)
+	if !found {
+		return nil, nil
+	}
+
+	if jo.noWait {
+		job := &Job{ID: id, Status: JobAccepted}
+		if jo.progress != nil {
+			jo.progress(job)
+		}
+		return job, nil
+	}
+
+	return u.WaitForJobWithContext(ctx, id, jo.progress)
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufm_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/openbce/kperf/pkg/ufm"
+	"github.com/openbce/kperf/pkg/ufm/ufmtest"
+)
+
+func TestWaitForAcceptedJob(t *testing.T) {
+	u, srv := newUFM(t, "")
+	// The GUIDs are accepted by 202 with the job only in the Location header.
+	srv.SetAsyncGUIDs(1)
+
+	if err := u.CreateIBNetwork(&ufm.IBNetwork{PKey: 0x100, GUIDs: []string{guid1}}); err != nil {
+		t.Fatalf("failed to create IB network: %v", err)
+	}
+
+	if jobPolls(srv) == 0 {
+		t.Errorf("the job of the GUIDs is not waited")
+	}
+}
+
+func TestNoWait(t *testing.T) {
+	u, srv := newUFM(t, "")
+	srv.AddIBNetwork(&ufm.IBNetwork{PKey: 0x100})
+	srv.SetAsyncGUIDs(1)
+
+	var jobs []*ufm.Job
+	progress := ufm.WithJobProgress(func(job *ufm.Job) { jobs = append(jobs, job) })
+	ib := &ufm.IBNetwork{PKey: 0x100, GUIDs: []string{guid1}}
+	if _, err := u.Patch(ib, ufm.GUIDField, ufm.AddStrategy, ufm.WithWait(false), progress); err != nil {
+		t.Fatalf("failed to patch IB network: %v", err)
+	}
+
+	if n := jobPolls(srv); n != 0 {
+		t.Errorf("polled the job %d times, expected none", n)
+	}
+	if len(jobs) != 1 || jobs[0].Status != ufm.JobAccepted {
+		t.Errorf("reported jobs %+v, expected one accepted", jobs)
+	}
+}
+
+func jobPolls(srv *ufmtest.Server) int {
+	polls := 0
+	for _, r := range srv.Requests() {
+		if r.Method == http.MethodGet && strings.HasPrefix(r.Path, "/ufmRest/jobs/") {
+			polls++
+		}
+	}
+	return polls
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+const (
+	linksPath = "/ufmRest/resources/links"
+)
+
+// IBLink is a cable between two ports in UFM; the ports are identified by the GUID of their
+// systems and the port numbers.
+type IBLink struct {
+	Name                 string `json:"name"`
+	SourceGUID           string `json:"source_guid"`
+	SourcePort           int32  `json:"source_port"`
+	SourcePortDName      string `json:"source_port_dname"`
+	DestinationGUID      string `json:"destination_guid"`
+	DestinationPort      int32  `json:"destination_port"`
+	DestinationPortDName string `json:"destination_port_dname"`
+	// The active width of the link, e.g. 4x.
+	Width string `json:"width"`
+	// The active speed of the link, e.g. HDR; the speed of the source port is used if empty.
+	Speed    string `json:"speed"`
+	Severity string `json:"severity"`
+}
+
+// Topology is the graph of the fabric; the nodes are the systems and the edges are the links
+// between their ports.
+type Topology struct {
+	Nodes []*TopologyNode `json:"nodes"`
+	Edges []*TopologyEdge `json:"edges"`
+}
+
+// TopologyNode is a system in the topology.
+type TopologyNode struct {
+	// The system ID, i.e. IBPort.SystemID.
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// The tier of the system in the fabric, e.g. 0 for the hosts.
+	Tier int32 `json:"tier"`
+	// The names of the linked ports of the system.
+	Ports []string `json:"ports"`
+}
+
+// TopologyEdge is a link between two ports in the topology.
+type TopologyEdge struct {
+	Source      TopologyEndpoint `json:"source"`
+	Destination TopologyEndpoint `json:"destination"`
+	Speed       string           `json:"speed"`
+	Width       string           `json:"width"`
+}
+
+// TopologyEndpoint is a port of a link.
+type TopologyEndpoint struct {
+	// The ID of the node of the port.
+	Node string `json:"node"`
+	// The name of the port, i.e. IBPort.Name.
+	Port   string `json:"port"`
+	GUID   string `json:"guid"`
+	Number int32  `json:"number"`
+	Tier   int32  `json:"tier"`
+	Path   string `json:"path"`
+}
+
+func (u *UFM) ListLinks() ([]*IBLink, *UFMError) {
+	return u.ListLinksWithContext(context.Background())
+}
+
+// ListLinksWithContext lists all the links in UFM.
+func (u *UFM) ListLinksWithContext(ctx context.Context) ([]*IBLink, *UFMError) {
+	data, err := u.client.GetWithContext(ctx, u.buildURL(linksPath))
+	if err != nil {
+		return nil, wrapError(err, "failed to list links")
+	}
+
+	links := []*IBLink{}
+	if err := json.Unmarshal(data, &links); err != nil {
+		return nil, &UFMError{
+			Code:    UnknownErr,
+			Message: fmt.Sprintf("failed to unmarshal links with error: %v", err),
+			Err:     err,
+		}
+	}
+
+	return links, nil
+}
+
+func (u *UFM) GetTopology(guids ...string) (*Topology, *UFMError) {
+	return u.GetTopologyWithContext(context.Background(), guids...)
+}
+
+// GetTopologyWithContext builds the topology from the links, ports and systems in UFM; only
+// the links of the port GUIDs and their nodes are returned if any, e.g. to find the leaf
+// switches of the hosts in a pkey.
+func (u *UFM) GetTopologyWithContext(ctx context.Context, guids ...string) (*Topology, *UFMError) {
+	links, ufmErr := u.ListLinksWithContext(ctx)
+	if ufmErr != nil {
+		return nil, ufmErr
+	}
+	ports, ufmErr := u.QueryPortsWithContext(ctx, nil)
+	if ufmErr != nil {
+		return nil, ufmErr
+	}
+	systems, ufmErr := u.ListSystemsWithContext(ctx)
+	if ufmErr != nil {
+		return nil, ufmErr
+	}
+
+	return buildTopology(links, ports, systems, guids), nil
+}
+
+// buildTopology joins the links with the ports by the port names, i.e. "<system guid>_<port number>".
+func buildTopology(links []*IBLink, ports []*IBPort, systems []*IBSystem, guids []string) *Topology {
+	portByName := map[string]*IBPort{}
+	for _, p := range ports {
+		portByName[p.Name] = p
+	}
+	systemByID := map[string]*IBSystem{}
+	for _, s := range systems {
+		systemByID[s.Name] = s
+	}
+	selected := map[string]bool{}
+	for _, g := range guids {
+		selected[normalizeGUID(g)] = true
+	}
+
+	res := &Topology{Nodes: []*TopologyNode{}, Edges: []*TopologyEdge{}}
+	nodes := map[string]*TopologyNode{}
+	addEndpoint := func(guid string, number int32) TopologyEndpoint {
+		name := fmt.Sprintf("%s_%d", normalizeGUID(guid), number)
+		ep := TopologyEndpoint{Node: normalizeGUID(guid), Port: name, Number: number}
+		nodeName := ep.Node
+		if p, found := portByName[name]; found {
+			ep.GUID, ep.Tier, ep.Path = p.GUID, p.Tier, p.Path
+			if p.SystemID != "" {
+				ep.Node = p.SystemID
+			}
+			if p.SystemName != "" {
+				nodeName = p.SystemName
+			}
+		}
+
+		n, found := nodes[ep.Node]
+		if !found {
+			n = &TopologyNode{ID: ep.Node, Name: nodeName, Tier: ep.Tier}
+			if s, found := systemByID[ep.Node]; found {
+				n.Type = s.Type
+				if s.SystemName != "" {
+					n.Name = s.SystemName
+				}
+			}
+			nodes[ep.Node] = n
+			res.Nodes = append(res.Nodes, n)
+		}
+		n.Ports = append(n.Ports, name)
+
+		return ep
+	}
+
+	for _, l := range links {
+		src := portByName[fmt.Sprintf("%s_%d", normalizeGUID(l.SourceGUID), l.SourcePort)]
+		dst := portByName[fmt.Sprintf("%s_%d", normalizeGUID(l.DestinationGUID), l.DestinationPort)]
+		if len(selected) != 0 && !(src != nil && selected[normalizeGUID(src.GUID)]) &&
+			!(dst != nil && selected[normalizeGUID(dst.GUID)]) {
+			continue
+		}
+
+		edge := &TopologyEdge{
+			Source:      addEndpoint(l.SourceGUID, l.SourcePort),
+			Destination: addEndpoint(l.DestinationGUID, l.DestinationPort),
+			Speed:       l.Speed,
+			Width:       l.Width,
+		}
+		if edge.Speed == "" && src != nil {
+			edge.Speed = src.ActiveSpeed
+		}
+		res.Edges = append(res.Edges, edge)
+	}
+
+	sort.Slice(res.Nodes, func(i, j int) bool {
+		if res.Nodes[i].Tier != res.Nodes[j].Tier {
+			return res.Nodes[i].Tier < res.Nodes[j].Tier
+		}
+		return res.Nodes[i].ID < res.Nodes[j].ID
+	})
+	for _, n := range res.Nodes {
+		sort.Strings(n.Ports)
+	}
+
+	return res
+}
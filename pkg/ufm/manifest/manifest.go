@@ -0,0 +1,190 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package manifest reads the desired IB networks from the YAML or JSON manifests, e.g.
+//
+//	name: storage
+//	pkey: 0x10
+//	guids:
+//	- "0x0002c903000e0b72"
+//	mtu: 4096
+//	ip_over_ib: true
+//	---
+//	name: compute
+//	pkey: 0x11
+//	...
+//
+// The fields are the same as the JSON output of ufm.IBNetwork; a document may also be a list of
+// IB networks. The GUIDs have to be quoted, otherwise YAML reads them as numbers.
+//
+// The snapshots written by "ufm export", i.e. ufm.Snapshot, are read by LoadSnapshotFile.
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/openbce/kperf/pkg/ufm"
+)
+
+var separator = regexp.MustCompile(`(?m)^---[ \t]*$`)
+
+// LoadFile reads the IB networks from the manifest file; "-" means stdin.
+func LoadFile(path string) ([]*ufm.IBNetwork, error) {
+	if path == "-" {
+		return Load(os.Stdin)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ibs, err := Load(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return ibs, nil
+}
+
+// Load reads the IB networks from the YAML or JSON documents; the unknown fields, invalid
+// and duplicated pkeys are rejected.
+func Load(r io.Reader) ([]*ufm.IBNetwork, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []*ufm.IBNetwork
+	for i, doc := range separator.Split(string(data), -1) {
+		ibs, err := decode([]byte(doc))
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %v", i+1, err)
+		}
+		res = append(res, ibs...)
+	}
+
+	if err := validate(res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// LoadSnapshotFile reads the YAML or JSON snapshot of ufm.Snapshot; "-" means stdin.
+func LoadSnapshotFile(path string) (*ufm.Snapshot, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot, err := LoadSnapshot(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return snapshot, nil
+}
+
+// LoadSnapshot reads the YAML or JSON snapshot; the version and partitions are validated.
+func LoadSnapshot(data []byte) (*ufm.Snapshot, error) {
+	data, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	snapshot := &ufm.Snapshot{}
+	if err := decoder.Decode(snapshot); err != nil {
+		return nil, err
+	}
+
+	if snapshot.Version != ufm.SnapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %q, expected %q", snapshot.Version, ufm.SnapshotVersion)
+	}
+	if err := validate(snapshot.Partitions); err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+func validate(ibs []*ufm.IBNetwork) error {
+	pkeys := map[int32]bool{}
+	for _, ib := range ibs {
+		if ib == nil {
+			return fmt.Errorf("empty IB network")
+		}
+		if ib.PKey == 0 || !ufm.IsPKeyValid(ib.PKey) {
+			return fmt.Errorf("invalid pkey 0x%04X of IB network %q", ib.PKey, ib.Name)
+		}
+		if pkeys[ib.PKey] {
+			return fmt.Errorf("duplicated pkey 0x%04X", ib.PKey)
+		}
+		pkeys[ib.PKey] = true
+	}
+
+	return nil
+}
+
+func decode(doc []byte) ([]*ufm.IBNetwork, error) {
+	data, err := yaml.YAMLToJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	if data[0] == '[' {
+		var ibs, res []*ufm.IBNetwork
+		if err := decoder.Decode(&ibs); err != nil {
+			return nil, err
+		}
+		for _, ib := range ibs {
+			if ib != nil {
+				res = append(res, ib)
+			}
+		}
+		return res, nil
+	}
+
+	ib := &ufm.IBNetwork{}
+	if err := decoder.Decode(ib); err != nil {
+		return nil, err
+	}
+
+	return []*ufm.IBNetwork{ib}, nil
+}
@@ -0,0 +1,272 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"time"
+)
+
+const (
+	monitoringStartPath   = "/ufmRest/monitoring/start"
+	monitoringSessionPath = "/ufmRest/monitoring/session/%s"
+	monitoringDataPath    = "/ufmRest/monitoring/session/%s/data"
+
+	// monitoringUpdatedKey is the key of the update time in the data of a monitoring session;
+	// the other keys are the port names.
+	monitoringUpdatedKey = "last_updated"
+)
+
+// The attributes of the port counters in the monitoring sessions of UFM.
+const (
+	XmitDataAttr     = "Infiniband_XmitData"
+	RcvDataAttr      = "Infiniband_RcvData"
+	XmitPacketsAttr  = "Infiniband_XmitPkts"
+	RcvPacketsAttr   = "Infiniband_RcvPkts"
+	SymbolErrorsAttr = "Infiniband_SymbolErrorCounter"
+	LinkDownedAttr   = "Infiniband_LinkDownedCounter"
+	XmitDiscardsAttr = "Infiniband_PortXmitDiscards"
+)
+
+var portCounterAttrs = []string{
+	XmitDataAttr, RcvDataAttr, XmitPacketsAttr, RcvPacketsAttr, SymbolErrorsAttr, LinkDownedAttr, XmitDiscardsAttr,
+}
+
+// PortCounters is the counters of a port; the data are in the units of UFM, i.e. 4 octets.
+type PortCounters struct {
+	XmitData     uint64 `json:"Infiniband_XmitData"`
+	RcvData      uint64 `json:"Infiniband_RcvData"`
+	XmitPackets  uint64 `json:"Infiniband_XmitPkts"`
+	RcvPackets   uint64 `json:"Infiniband_RcvPkts"`
+	SymbolErrors uint64 `json:"Infiniband_SymbolErrorCounter"`
+	LinkDowned   uint64 `json:"Infiniband_LinkDownedCounter"`
+	XmitDiscards uint64 `json:"Infiniband_PortXmitDiscards"`
+}
+
+// PortCounterRates is the increase of the PortCounters per second.
+type PortCounterRates struct {
+	XmitData     float64 `json:"xmit_data"`
+	RcvData      float64 `json:"rcv_data"`
+	XmitPackets  float64 `json:"xmit_packets"`
+	RcvPackets   float64 `json:"rcv_packets"`
+	SymbolErrors float64 `json:"symbol_errors"`
+	LinkDowned   float64 `json:"link_downed"`
+	XmitDiscards float64 `json:"xmit_discards"`
+}
+
+// MonitoringSession samples the counters of the ports in UFM until deleted.
+type MonitoringSession struct {
+	ID string `json:"id"`
+	// The names of the ports, i.e. IBPort.Name.
+	Ports    []string      `json:"ports"`
+	Interval time.Duration `json:"interval"`
+}
+
+// PortSample is the counters of the ports in a monitoring session at a time.
+type PortSample struct {
+	Time     time.Time                `json:"time"`
+	Counters map[string]*PortCounters `json:"counters"`
+}
+
+// PortStats is the counters of a port and their rates between two samples.
+type PortStats struct {
+	Port     string           `json:"port"`
+	Counters PortCounters     `json:"counters"`
+	Rates    PortCounterRates `json:"rates"`
+}
+
+func (u *UFM) CreateMonitoringSession(ports []string, interval time.Duration) (*MonitoringSession, *UFMError) {
+	return u.CreateMonitoringSessionWithContext(context.Background(), ports, interval)
+}
+
+// CreateMonitoringSessionWithContext starts a monitoring session of the port counters; UFM
+// samples the ports every interval, which is rounded up to seconds.
+func (u *UFM) CreateMonitoringSessionWithContext(ctx context.Context, ports []string, interval time.Duration) (*MonitoringSession, *UFMError) {
+	if len(ports) == 0 {
+		return nil, &UFMError{
+			Code:    BadRequestErr,
+			Message: "no ports to monitor",
+		}
+	}
+
+	seconds := int((interval + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	req := struct {
+		ScopeObject string   `json:"scope_object"`
+		Ports       []string `json:"ports"`
+		Attributes  []string `json:"attributes"`
+		Interval    int      `json:"interval"`
+	}{
+		ScopeObject: "port",
+		Ports:       ports,
+		Attributes:  portCounterAttrs,
+		Interval:    seconds,
+	}
+	body, err := json.Marshal(&req)
+	if err != nil {
+		return nil, &UFMError{
+			Code:    UnknownErr,
+			Message: fmt.Sprintf("failed to marshal monitoring session with error: %v", err),
+			Err:     err,
+		}
+	}
+
+	resp, ufmErr := u.client.DoWithContext(ctx, http.MethodPost, u.buildURL(monitoringStartPath), body)
+	if ufmErr != nil {
+		return nil, wrapError(ufmErr, "failed to create monitoring session")
+	}
+
+	// UFM refers to the session by its ID, or by its path in the body or in the Location header,
+	// e.g. /ufmRest/monitoring/session/1.
+	res := struct {
+		ID       json.Number `json:"id"`
+		Location string      `json:"location"`
+	}{}
+	if len(bytes.TrimSpace(resp.Body)) != 0 {
+		err = json.Unmarshal(resp.Body, &res)
+	}
+	if res.Location == "" {
+		res.Location = resp.Header.Get("Location")
+	}
+	id := res.ID.String()
+	if id == "" && res.Location != "" {
+		id = path.Base(res.Location)
+	}
+	if err != nil || id == "" {
+		return nil, &UFMError{
+			Code:    UnknownErr,
+			Message: fmt.Sprintf("failed to get the ID of monitoring session from %q", string(resp.Body)),
+			Err:     err,
+		}
+	}
+
+	return &MonitoringSession{ID: id, Ports: ports, Interval: time.Duration(seconds) * time.Second}, nil
+}
+
+func (u *UFM) SampleMonitoringSession(id string) (*PortSample, *UFMError) {
+	return u.SampleMonitoringSessionWithContext(context.Background(), id)
+}
+
+// SampleMonitoringSessionWithContext gets the latest counters of the ports in the monitoring session.
+func (u *UFM) SampleMonitoringSessionWithContext(ctx context.Context, id string) (*PortSample, *UFMError) {
+	data, ufmErr := u.client.GetWithContext(ctx, u.buildURL(fmt.Sprintf(monitoringDataPath, id)))
+	if ufmErr != nil {
+		return nil, wrapError(ufmErr, "failed to sample monitoring session %s", id)
+	}
+
+	raw := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, &UFMError{
+			Code:    UnknownErr,
+			Message: fmt.Sprintf("failed to unmarshal monitoring data with error: %v", err),
+			Err:     err,
+		}
+	}
+
+	res := &PortSample{Time: time.Now(), Counters: map[string]*PortCounters{}}
+	for key, value := range raw {
+		if key == monitoringUpdatedKey {
+			var ts string
+			if err := json.Unmarshal(value, &ts); err == nil {
+				if t, err := parseTimestamp(ts); err == nil {
+					res.Time = t
+				}
+			}
+			continue
+		}
+
+		counters := &PortCounters{}
+		if err := json.Unmarshal(value, counters); err != nil {
+			return nil, &UFMError{
+				Code:    UnknownErr,
+				Message: fmt.Sprintf("failed to unmarshal counters of port %s with error: %v", key, err),
+				Err:     err,
+			}
+		}
+		res.Counters[key] = counters
+	}
+
+	return res, nil
+}
+
+func (u *UFM) DeleteMonitoringSession(id string) *UFMError {
+	return u.DeleteMonitoringSessionWithContext(context.Background(), id)
+}
+
+// DeleteMonitoringSessionWithContext stops the monitoring session; it's not an error if the
+// session is not found, e.g. expired.
+func (u *UFM) DeleteMonitoringSessionWithContext(ctx context.Context, id string) *UFMError {
+	if _, ufmErr := u.client.DeleteWithContext(ctx, u.buildURL(fmt.Sprintf(monitoringSessionPath, id))); ufmErr != nil {
+		if ufmErr.IsNotFound() {
+			return nil
+		}
+		return wrapError(ufmErr, "failed to delete monitoring session %s", id)
+	}
+
+	return nil
+}
+
+// ComputePortStats returns the counters of the ports in cur with their rates since prev,
+// sorted by the port names; the rates are zero without prev, and a counter less than in
+// prev is taken as reset, i.e. increased from zero.
+func ComputePortStats(prev, cur *PortSample) []*PortStats {
+	var seconds float64
+	if prev != nil {
+		seconds = cur.Time.Sub(prev.Time).Seconds()
+	}
+
+	rate := func(from, to uint64) float64 {
+		if seconds <= 0 {
+			return 0
+		}
+		if to < from {
+			return float64(to) / seconds
+		}
+		return float64(to-from) / seconds
+	}
+
+	res := []*PortStats{}
+	for port, c := range cur.Counters {
+		stats := &PortStats{Port: port, Counters: *c}
+		// The rates of the ports new in the session are unknown, i.e. zero.
+		p := c
+		if prev != nil && prev.Counters[port] != nil {
+			p = prev.Counters[port]
+		}
+		stats.Rates = PortCounterRates{
+			XmitData:     rate(p.XmitData, c.XmitData),
+			RcvData:      rate(p.RcvData, c.RcvData),
+			XmitPackets:  rate(p.XmitPackets, c.XmitPackets),
+			RcvPackets:   rate(p.RcvPackets, c.RcvPackets),
+			SymbolErrors: rate(p.SymbolErrors, c.SymbolErrors),
+			LinkDowned:   rate(p.LinkDowned, c.LinkDowned),
+			XmitDiscards: rate(p.XmitDiscards, c.XmitDiscards),
+		}
+		res = append(res, stats)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Port < res[j].Port })
+
+	return res
+}
@@ -0,0 +1,42 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufm_test
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMonitoringSession(t *testing.T) {
+	u, srv := newUFM(t, "")
+
+	// The session is accepted by 202 with its path only in the Location header.
+	session, err := u.CreateMonitoringSession([]string{"0002c90300a1b2c1_1"}, time.Second)
+	if err != nil {
+		t.Fatalf("failed to create monitoring session: %v", err)
+	}
+	if _, found := srv.MonitoringSession(session.ID); !found {
+		t.Fatalf("monitoring session %q is not created", session.ID)
+	}
+
+	if err := u.DeleteMonitoringSession(session.ID); err != nil {
+		t.Fatalf("failed to delete monitoring session: %v", err)
+	}
+	if _, found := srv.MonitoringSession(session.ID); found {
+		t.Errorf("monitoring session %q is not deleted", session.ID)
+	}
+}
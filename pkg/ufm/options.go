@@ -0,0 +1,95 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufm
+
+import (
+	"net/http"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+const defaultUserAgent = "kperf-ufm"
+
+// Option customizes the UFM created by NewUFMWithConfig and the ufmclient created by NewClient.
+type Option func(*options)
+
+type options struct {
+	client      UFMClient
+	httpClient  *http.Client
+	retryPolicy *RetryPolicy
+	logger      zerolog.Logger
+	userAgent   string
+	allocator   *PKeyAllocator
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{
+		logger:    log.Logger,
+		userAgent: defaultUserAgent,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithClient sets the UFMClient of the UFM, e.g. a fake one for testing; the
+// credentials, TLS and client related options are ignored if set.
+func WithClient(client UFMClient) Option {
+	return func(o *options) {
+		o.client = client
+	}
+}
+
+// WithHTTPClient sets the http.Client of the ufmclient; its transport is used as it
+// is, so the TLS config is ignored if set.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = httpClient
+	}
+}
+
+// WithRetryPolicy sets the retry policy of the ufmclient; DefaultRetryPolicy is used if not set.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(o *options) {
+		o.retryPolicy = &policy
+	}
+}
+
+// WithLogger sets the logger of the ufmclient; the global logger is used if not set.
+func WithLogger(logger zerolog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithUserAgent sets the User-Agent header of the requests to UFM.
+func WithUserAgent(userAgent string) Option {
+	return func(o *options) {
+		o.userAgent = userAgent
+	}
+}
+
+// WithPKeyAllocator sets the allocator of the pkeys for the IB networks created without pkey;
+// the allocator of UFMConfig is used if not set.
+func WithPKeyAllocator(allocator *PKeyAllocator) Option {
+	return func(o *options) {
+		o.allocator = allocator
+	}
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufm
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// SystemType is the type of the system of the ports in UFM.
+type SystemType string
+
+const (
+	ComputerSystem SystemType = "Computer"
+	SwitchSystem   SystemType = "Switch"
+	GatewaySystem  SystemType = "Gateway"
+)
+
+// ParseSystemType parses the system type case insensitively; empty means all the types.
+func ParseSystemType(s string) (SystemType, error) {
+	for _, t := range []SystemType{ComputerSystem, SwitchSystem, GatewaySystem} {
+		if strings.EqualFold(s, string(t)) {
+			return t, nil
+		}
+	}
+	if s == "" {
+		return "", nil
+	}
+
+	return "", fmt.Errorf("invalid system type %q, one of 'Computer', 'Switch' or 'Gateway'", s)
+}
+
+// PortQuery selects the ports by QueryPorts; the empty options match all the ports. The
+// SystemType and SystemName are filtered by UFM, and the others are filtered locally.
+type PortQuery struct {
+	SystemType SystemType
+	// The name of the system of the ports, e.g. the hostname.
+	SystemName string
+	// The logical state of the ports, e.g. Active; matched case insensitively.
+	LogicalState string
+	// The physical state of the ports, e.g. LinkUp; matched case insensitively.
+	PhysicalState string
+	// The active speed of the ports, e.g. HDR; matched case insensitively.
+	ActiveSpeed string
+	// The tier of the ports; nil matches all the tiers.
+	Tier  *int32
+	GUIDs []string
+}
+
+// values returns the options filtered by UFM.
+func (q *PortQuery) values() url.Values {
+	values := url.Values{}
+	if q.SystemType != "" {
+		values.Set("sys_type", string(q.SystemType))
+	}
+	if q.SystemName != "" {
+		values.Set("system", q.SystemName)
+	}
+
+	return values
+}
+
+// matcher returns the local filter of the ports; the options filtered by UFM are checked
+// again, in case UFM ignores them.
+func (q *PortQuery) matcher() func(p *IBPort) bool {
+	guids := map[string]bool{}
+	for _, g := range q.GUIDs {
+		guids[normalizeGUID(g)] = true
+	}
+
+	matchString := func(want, got string) bool {
+		return want == "" || strings.EqualFold(want, got)
+	}
+
+	return func(p *IBPort) bool {
+		if len(guids) != 0 && !guids[normalizeGUID(p.GUID)] {
+			return false
+		}
+		if q.Tier != nil && *q.Tier != p.Tier {
+			return false
+		}
+
+		return matchString(q.SystemName, p.SystemName) &&
+			matchString(q.LogicalState, p.LogicalState) &&
+			matchString(q.PhysicalState, p.PhysicalState) &&
+			matchString(q.ActiveSpeed, p.ActiveSpeed)
+	}
+}
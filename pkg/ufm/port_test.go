@@ -0,0 +1,56 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufm_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/openbce/kperf/pkg/ufm"
+)
+
+func TestQueryPorts(t *testing.T) {
+	u, srv := newUFM(t, "")
+	srv.AddPort(ufm.IBPort{Name: "0002c90300a1b2c1_1", GUID: guid1, SystemName: "host-1", LogicalState: "Active"}, "Computer")
+	srv.AddPort(ufm.IBPort{Name: "0002c90300a1b2c2_1", GUID: guid2, SystemName: "host-2", LogicalState: "Down"}, "Computer")
+	srv.AddPort(ufm.IBPort{Name: "0002c90300a1b2c3_1", GUID: guid3, SystemName: "switch-1", LogicalState: "Active"}, "Switch")
+
+	ports, err := u.ListPort()
+	if err != nil {
+		t.Fatalf("failed to list ports: %v", err)
+	}
+	if len(ports) != 2 {
+		t.Errorf("listed %d ports of computers, expected 2", len(ports))
+	}
+
+	ports, err = u.ListPort(guid2)
+	if err != nil {
+		t.Fatalf("failed to list ports: %v", err)
+	}
+	if len(ports) != 1 || ports[0].GUID != guid2 {
+		t.Errorf("unexpected ports of %s: %+v", guid2, ports)
+	}
+
+	// No matched port is an empty list rather than null.
+	ports, err = u.ListPort("0x0002c90300a1b2ff")
+	if err != nil {
+		t.Fatalf("failed to list ports: %v", err)
+	}
+	if data, _ := json.Marshal(ports); string(data) != "[]" {
+		t.Errorf("unmatched ports are %s, expected []", data)
+	}
+}
@@ -0,0 +1,229 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package printer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openbce/kperf/pkg/ufm"
+)
+
+var ibNetworkColumns = []Column{
+	{Header: "NAME", Value: func(obj interface{}) string { return obj.(*ufm.IBNetwork).Name }},
+	{Header: "PKEY", Value: func(obj interface{}) string { return fmt.Sprintf("0x%04x", obj.(*ufm.IBNetwork).PKey) }},
+	{Header: "SHARP", Value: func(obj interface{}) string { return strconv.FormatBool(obj.(*ufm.IBNetwork).EnableSharp) }},
+	{Header: "IPOIB", Value: func(obj interface{}) string { return strconv.FormatBool(obj.(*ufm.IBNetwork).IPOverIB) }},
+	{Header: "MTU", Value: func(obj interface{}) string { return strconv.Itoa(int(obj.(*ufm.IBNetwork).MTU)) }},
+	{Header: "RATE", Value: func(obj interface{}) string { return fmt.Sprintf("%.2f", obj.(*ufm.IBNetwork).RateLimit) }},
+	{Header: "LEVEL", Value: func(obj interface{}) string { return strconv.Itoa(int(obj.(*ufm.IBNetwork).ServiceLevel)) }},
+	{Header: "GUID#", Value: func(obj interface{}) string { return strconv.Itoa(len(obj.(*ufm.IBNetwork).GUIDs)) }},
+	{Header: "INDEX0", Wide: true, Value: func(obj interface{}) string { return strconv.FormatBool(obj.(*ufm.IBNetwork).Index0) }},
+	{Header: "GUIDS", Wide: true, Value: func(obj interface{}) string { return noneIfEmpty(joinGUIDMembers(obj.(*ufm.IBNetwork))) }},
+}
+
+var ibPortColumns = []Column{
+	{Header: "NAME", Value: func(obj interface{}) string { return obj.(*ufm.IBPort).Name }},
+	{Header: "GUID", Value: func(obj interface{}) string { return obj.(*ufm.IBPort).GUID }},
+	{Header: "SYSTEMID", Value: func(obj interface{}) string { return obj.(*ufm.IBPort).SystemID }},
+	{Header: "SYSTEMNAME", Value: func(obj interface{}) string { return noneIfEmpty(obj.(*ufm.IBPort).SystemName) }},
+	{Header: "DNAME", Value: func(obj interface{}) string { return noneIfEmpty(obj.(*ufm.IBPort).DName) }},
+	{Header: "LID", Value: func(obj interface{}) string { return strconv.Itoa(int(obj.(*ufm.IBPort).LID)) }},
+	{Header: "LOGICALSTATE", Value: func(obj interface{}) string { return obj.(*ufm.IBPort).LogicalState }},
+	{Header: "PHYSICALSTATE", Value: func(obj interface{}) string { return obj.(*ufm.IBPort).PhysicalState }},
+	{Header: "SPEED", Wide: true, Value: func(obj interface{}) string { return noneIfEmpty(obj.(*ufm.IBPort).ActiveSpeed) }},
+	{Header: "MTU", Wide: true, Value: func(obj interface{}) string { return strconv.Itoa(int(obj.(*ufm.IBPort).MTU)) }},
+	{Header: "TIER", Wide: true, Value: func(obj interface{}) string { return strconv.Itoa(int(obj.(*ufm.IBPort).Tier)) }},
+}
+
+var ibSystemColumns = []Column{
+	{Header: "NAME", Value: func(obj interface{}) string { return obj.(*ufm.IBSystem).Name }},
+	{Header: "SYSTEMNAME", Value: func(obj interface{}) string { return noneIfEmpty(obj.(*ufm.IBSystem).SystemName) }},
+	{Header: "TYPE", Value: func(obj interface{}) string { return noneIfEmpty(obj.(*ufm.IBSystem).Type) }},
+	{Header: "MODEL", Value: func(obj interface{}) string { return noneIfEmpty(obj.(*ufm.IBSystem).Model) }},
+	{Header: "FIRMWARE", Value: func(obj interface{}) string { return noneIfEmpty(obj.(*ufm.IBSystem).FirmwareVersion) }},
+	{Header: "ROLE", Value: func(obj interface{}) string { return noneIfEmpty(obj.(*ufm.IBSystem).Role) }},
+	{Header: "IP", Value: func(obj interface{}) string { return noneIfEmpty(obj.(*ufm.IBSystem).IP) }},
+	{Header: "PORTS", Value: func(obj interface{}) string { return strconv.Itoa(len(obj.(*ufm.IBSystem).Ports)) }},
+	{Header: "STATE", Value: func(obj interface{}) string { return noneIfEmpty(obj.(*ufm.IBSystem).State) }},
+	{Header: "GUID", Wide: true, Value: func(obj interface{}) string { return noneIfEmpty(obj.(*ufm.IBSystem).GUID) }},
+	{Header: "VENDOR", Wide: true, Value: func(obj interface{}) string { return noneIfEmpty(obj.(*ufm.IBSystem).Vendor) }},
+	{Header: "DESCRIPTION", Wide: true, Value: func(obj interface{}) string { return noneIfEmpty(obj.(*ufm.IBSystem).Description) }},
+}
+
+var topologyEdgeColumns = []Column{
+	{Header: "SOURCE", Value: func(obj interface{}) string { return obj.(*ufm.TopologyEdge).Source.Node }},
+	{Header: "SPORT", Value: func(obj interface{}) string { return strconv.Itoa(int(obj.(*ufm.TopologyEdge).Source.Number)) }},
+	{Header: "DESTINATION", Value: func(obj interface{}) string { return obj.(*ufm.TopologyEdge).Destination.Node }},
+	{Header: "DPORT", Value: func(obj interface{}) string { return strconv.Itoa(int(obj.(*ufm.TopologyEdge).Destination.Number)) }},
+	{Header: "SPEED", Value: func(obj interface{}) string { return noneIfEmpty(obj.(*ufm.TopologyEdge).Speed) }},
+	{Header: "WIDTH", Value: func(obj interface{}) string { return noneIfEmpty(obj.(*ufm.TopologyEdge).Width) }},
+	{Header: "SGUID", Wide: true, Value: func(obj interface{}) string { return noneIfEmpty(obj.(*ufm.TopologyEdge).Source.GUID) }},
+	{Header: "STIER", Wide: true, Value: func(obj interface{}) string { return strconv.Itoa(int(obj.(*ufm.TopologyEdge).Source.Tier)) }},
+	{Header: "DGUID", Wide: true, Value: func(obj interface{}) string { return noneIfEmpty(obj.(*ufm.TopologyEdge).Destination.GUID) }},
+	{Header: "DTIER", Wide: true, Value: func(obj interface{}) string { return strconv.Itoa(int(obj.(*ufm.TopologyEdge).Destination.Tier)) }},
+}
+
+var ibEventColumns = []Column{
+	{Header: "ID", Value: func(obj interface{}) string { return strconv.FormatInt(obj.(*ufm.IBEvent).ID, 10) }},
+	{Header: "TIME", Value: func(obj interface{}) string { return obj.(*ufm.IBEvent).Timestamp }},
+	{Header: "SEVERITY", Value: func(obj interface{}) string { return string(obj.(*ufm.IBEvent).Severity) }},
+	{Header: "CATEGORY", Value: func(obj interface{}) string { return noneIfEmpty(obj.(*ufm.IBEvent).Category) }},
+	{Header: "OBJECT", Value: func(obj interface{}) string { return noneIfEmpty(obj.(*ufm.IBEvent).ObjectName) }},
+	{Header: "DESCRIPTION", Value: func(obj interface{}) string { return noneIfEmpty(obj.(*ufm.IBEvent).Description) }},
+	{Header: "NAME", Wide: true, Value: func(obj interface{}) string { return noneIfEmpty(obj.(*ufm.IBEvent).Name) }},
+	{Header: "TYPE", Wide: true, Value: func(obj interface{}) string { return noneIfEmpty(obj.(*ufm.IBEvent).ObjectType) }},
+	{Header: "PATH", Wide: true, Value: func(obj interface{}) string { return noneIfEmpty(obj.(*ufm.IBEvent).ObjectPath) }},
+	{Header: "COUNTER", Wide: true, Value: func(obj interface{}) string { return strconv.FormatInt(obj.(*ufm.IBEvent).Counter, 10) }},
+}
+
+var ibAlarmColumns = []Column{
+	{Header: "ID", Value: func(obj interface{}) string { return strconv.FormatInt(obj.(*ufm.IBAlarm).ID, 10) }},
+	{Header: "TIME", Value: func(obj interface{}) string { return obj.(*ufm.IBAlarm).Timestamp }},
+	{Header: "SEVERITY", Value: func(obj interface{}) string { return string(obj.(*ufm.IBAlarm).Severity) }},
+	{Header: "OBJECT", Value: func(obj interface{}) string { return noneIfEmpty(obj.(*ufm.IBAlarm).ObjectName) }},
+	{Header: "NAME", Value: func(obj interface{}) string { return noneIfEmpty(obj.(*ufm.IBAlarm).Name) }},
+	{Header: "DESCRIPTION", Value: func(obj interface{}) string { return noneIfEmpty(obj.(*ufm.IBAlarm).Description) }},
+	{Header: "TYPE", Wide: true, Value: func(obj interface{}) string { return noneIfEmpty(obj.(*ufm.IBAlarm).ObjectType) }},
+	{Header: "REASON", Wide: true, Value: func(obj interface{}) string { return noneIfEmpty(obj.(*ufm.IBAlarm).Reason) }},
+	{Header: "COUNTER", Wide: true, Value: func(obj interface{}) string { return strconv.FormatInt(obj.(*ufm.IBAlarm).Counter, 10) }},
+}
+
+var portStatsColumns = []Column{
+	{Header: "PORT", Value: func(obj interface{}) string { return obj.(*ufm.PortStats).Port }},
+	{Header: "XMITDATA/S", Value: func(obj interface{}) string { return formatRate(obj.(*ufm.PortStats).Rates.XmitData) }},
+	{Header: "RCVDATA/S", Value: func(obj interface{}) string { return formatRate(obj.(*ufm.PortStats).Rates.RcvData) }},
+	{Header: "XMITPKTS/S", Value: func(obj interface{}) string { return formatRate(obj.(*ufm.PortStats).Rates.XmitPackets) }},
+	{Header: "RCVPKTS/S", Value: func(obj interface{}) string { return formatRate(obj.(*ufm.PortStats).Rates.RcvPackets) }},
+	{Header: "SYMBOLERRORS", Value: func(obj interface{}) string { return formatCounter(obj.(*ufm.PortStats).Counters.SymbolErrors) }},
+	{Header: "LINKDOWNED", Value: func(obj interface{}) string { return formatCounter(obj.(*ufm.PortStats).Counters.LinkDowned) }},
+	{Header: "XMITDISCARDS", Value: func(obj interface{}) string { return formatCounter(obj.(*ufm.PortStats).Counters.XmitDiscards) }},
+	{Header: "XMITDATA", Wide: true, Value: func(obj interface{}) string { return formatCounter(obj.(*ufm.PortStats).Counters.XmitData) }},
+	{Header: "RCVDATA", Wide: true, Value: func(obj interface{}) string { return formatCounter(obj.(*ufm.PortStats).Counters.RcvData) }},
+	{Header: "XMITPKTS", Wide: true, Value: func(obj interface{}) string { return formatCounter(obj.(*ufm.PortStats).Counters.XmitPackets) }},
+	{Header: "RCVPKTS", Wide: true, Value: func(obj interface{}) string { return formatCounter(obj.(*ufm.PortStats).Counters.RcvPackets) }},
+}
+
+var portActionResultColumns = []Column{
+	{Header: "GUID", Value: func(obj interface{}) string { return obj.(*ufm.PortActionResult).GUID }},
+	{Header: "PORT", Value: func(obj interface{}) string { return obj.(*ufm.PortActionResult).Port }},
+	{Header: "SYSTEM", Value: func(obj interface{}) string { return noneIfEmpty(obj.(*ufm.PortActionResult).System) }},
+	{Header: "ACTION", Value: func(obj interface{}) string { return string(obj.(*ufm.PortActionResult).Action) }},
+	{Header: "STATUS", Value: func(obj interface{}) string { return noneIfEmpty(string(obj.(*ufm.PortActionResult).Status)) }},
+	{Header: "JOB", Wide: true, Value: func(obj interface{}) string { return noneIfEmpty(obj.(*ufm.PortActionResult).JobID) }},
+	{Header: "SUMMARY", Wide: true, Value: func(obj interface{}) string { return noneIfEmpty(obj.(*ufm.PortActionResult).Summary) }},
+}
+
+var watchEventColumns = []Column{
+	{Header: "TIME", Value: func(obj interface{}) string { return obj.(*ufm.Event).Time.Format(time.RFC3339) }},
+	{Header: "TYPE", Value: func(obj interface{}) string { return string(obj.(*ufm.Event).Type) }},
+	{Header: "KIND", Value: func(obj interface{}) string { return string(obj.(*ufm.Event).Kind) }},
+	{Header: "PKEY", Value: func(obj interface{}) string { return fmt.Sprintf("0x%04x", obj.(*ufm.Event).PKey) }},
+	{Header: "NAME", Value: func(obj interface{}) string { return noneIfEmpty(obj.(*ufm.Event).IBNetwork.Name) }},
+	{Header: "GUID", Value: func(obj interface{}) string { return memberGUID(obj.(*ufm.Event).Member) }},
+	{Header: "MEMBERSHIP", Value: func(obj interface{}) string { return memberMembership(obj.(*ufm.Event).Member) }},
+	{Header: "IPOIB", Wide: true, Value: func(obj interface{}) string { return strconv.FormatBool(obj.(*ufm.Event).IBNetwork.IPOverIB) }},
+	{Header: "MTU", Wide: true, Value: func(obj interface{}) string { return strconv.Itoa(int(obj.(*ufm.Event).IBNetwork.MTU)) }},
+	{Header: "RATE", Wide: true, Value: func(obj interface{}) string { return fmt.Sprintf("%.2f", obj.(*ufm.Event).IBNetwork.RateLimit) }},
+	{Header: "LEVEL", Wide: true, Value: func(obj interface{}) string { return strconv.Itoa(int(obj.(*ufm.Event).IBNetwork.ServiceLevel)) }},
+}
+
+func memberGUID(m *ufm.GUIDMember) string {
+	if m == nil {
+		return "<none>"
+	}
+	return m.GUID
+}
+
+func memberMembership(m *ufm.GUIDMember) string {
+	if m == nil {
+		return "<none>"
+	}
+	if m.Index0 {
+		return string(m.Membership) + ",index0"
+	}
+	return string(m.Membership)
+}
+
+var restoreSummaryColumns = []Column{
+	{Header: "NAME", Value: func(obj interface{}) string { return noneIfEmpty(obj.(*ufm.RestoreSummary).Name) }},
+	{Header: "PKEY", Value: func(obj interface{}) string { return fmt.Sprintf("0x%04x", obj.(*ufm.RestoreSummary).PKey) }},
+	{Header: "ACTION", Value: func(obj interface{}) string { return restoreAction(obj.(*ufm.RestoreSummary)) }},
+	{Header: "STEPS", Value: func(obj interface{}) string { return strconv.Itoa(len(obj.(*ufm.RestoreSummary).Steps)) }},
+	{Header: "ERROR", Value: func(obj interface{}) string { return restoreError(obj.(*ufm.RestoreSummary)) }},
+	{Header: "CHANGES", Wide: true, Value: func(obj interface{}) string { return noneIfEmpty(joinSteps(obj.(*ufm.RestoreSummary).Steps)) }},
+}
+
+func restoreAction(s *ufm.RestoreSummary) string {
+	if s.Err != nil {
+		return "failed"
+	}
+	return string(s.Action)
+}
+
+func restoreError(s *ufm.RestoreSummary) string {
+	if s.Err == nil {
+		return "<none>"
+	}
+	return s.Err.Error()
+}
+
+func joinSteps(steps []ufm.ApplyStep) string {
+	var res []string
+	for _, s := range steps {
+		res = append(res, s.String())
+	}
+	return strings.Join(res, "; ")
+}
+
+func formatRate(r float64) string {
+	return strconv.FormatFloat(r, 'f', 2, 64)
+}
+
+func formatCounter(c uint64) string {
+	return strconv.FormatUint(c, 10)
+}
+
+func joinGUIDMembers(ib *ufm.IBNetwork) string {
+	var res []string
+	for _, m := range ib.GUIDMembers() {
+		res = append(res, m.String())
+	}
+	return strings.Join(res, ",")
+}
+
+func noneIfEmpty(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
+
+func init() {
+	RegisterColumns(&ufm.IBNetwork{}, ibNetworkColumns)
+	RegisterColumns(&ufm.IBPort{}, ibPortColumns)
+	RegisterColumns(&ufm.IBSystem{}, ibSystemColumns)
+	RegisterColumns(&ufm.TopologyEdge{}, topologyEdgeColumns)
+	RegisterColumns(&ufm.IBEvent{}, ibEventColumns)
+	RegisterColumns(&ufm.IBAlarm{}, ibAlarmColumns)
+	RegisterColumns(&ufm.PortStats{}, portStatsColumns)
+	RegisterColumns(&ufm.PortActionResult{}, portActionResultColumns)
+	RegisterColumns(&ufm.RestoreSummary{}, restoreSummaryColumns)
+	RegisterColumns(&ufm.Event{}, watchEventColumns)
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package printer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/openbce/kperf/pkg/ufm"
+)
+
+// dotPrinter prints the ufm.Topology in the DOT language of Graphviz, e.g. `dot -Tsvg`.
+type dotPrinter struct{}
+
+func (p *dotPrinter) Print(w io.Writer, obj interface{}) error {
+	t, ok := obj.(*ufm.Topology)
+	if !ok {
+		return fmt.Errorf("the %s format only supports the topology, got %T", DOTFormat, obj)
+	}
+
+	var b strings.Builder
+	b.WriteString("graph fabric {\n")
+	b.WriteString("    node [shape=box];\n")
+	for _, n := range t.Nodes {
+		fmt.Fprintf(&b, "    %s [label=%s, type=%s, tier=%d];\n",
+			dotQuote(n.ID), dotQuote(n.Name), dotQuote(n.Type), n.Tier)
+	}
+	for _, e := range t.Edges {
+		fmt.Fprintf(&b, "    %s -- %s [label=%s, taillabel=%s, headlabel=%s, speed=%s, width=%s];\n",
+			dotQuote(e.Source.Node), dotQuote(e.Destination.Node),
+			dotQuote(strings.TrimSpace(e.Speed+" "+e.Width)),
+			dotQuote(strconv.Itoa(int(e.Source.Number))), dotQuote(strconv.Itoa(int(e.Destination.Number))),
+			dotQuote(e.Speed), dotQuote(e.Width))
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// dotQuote quotes the ID of DOT, in which only the double quotes and backslashes are escaped.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+var graphMLKeys = []graphMLKey{
+	{ID: "name", For: "node", Name: "name", Type: "string"},
+	{ID: "type", For: "node", Name: "type", Type: "string"},
+	{ID: "tier", For: "node", Name: "tier", Type: "int"},
+	{ID: "source_port", For: "edge", Name: "source_port", Type: "string"},
+	{ID: "target_port", For: "edge", Name: "target_port", Type: "string"},
+	{ID: "speed", For: "edge", Name: "speed", Type: "string"},
+	{ID: "width", For: "edge", Name: "width", Type: "string"},
+}
+
+// graphMLPrinter prints the ufm.Topology in GraphML, e.g. for Gephi or yEd.
+type graphMLPrinter struct{}
+
+func (p *graphMLPrinter) Print(w io.Writer, obj interface{}) error {
+	t, ok := obj.(*ufm.Topology)
+	if !ok {
+		return fmt.Errorf("the %s format only supports the topology, got %T", GraphMLFormat, obj)
+	}
+
+	doc := graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys:  graphMLKeys,
+		Graph: graphMLGraph{ID: "fabric", EdgeDefault: "undirected"},
+	}
+	for _, n := range t.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{
+			ID: n.ID,
+			Data: []graphMLData{
+				{Key: "name", Value: n.Name},
+				{Key: "type", Value: n.Type},
+				{Key: "tier", Value: strconv.Itoa(int(n.Tier))},
+			},
+		})
+	}
+	for _, e := range t.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			Source: e.Source.Node,
+			Target: e.Destination.Node,
+			Data: []graphMLData{
+				{Key: "source_port", Value: e.Source.Port},
+				{Key: "target_port", Value: e.Destination.Port},
+				{Key: "speed", Value: e.Speed},
+				{Key: "width", Value: e.Width},
+			},
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "    ")
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
@@ -0,0 +1,299 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package printer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The jsonpath template is a subset of kubectl's, e.g.
+//
+//	{[*].name}                          the names of all the objects in the list
+//	{range [*]}{.name}{"\t"}{.pkey}{"\n"}{end}
+//	{.guids[0]}                         the first GUID of the object
+//
+// The paths are relative to the current object, which is the printed object or
+// the item in range; the leading "." or "$" is optional.
+
+type templateNode interface{}
+
+type textNode string
+
+type pathNode []pathSegment
+
+type rangeNode struct {
+	path pathNode
+	body []templateNode
+}
+
+type pathSegment struct {
+	field string
+	index int
+	all   bool
+	isIdx bool
+}
+
+type jsonPathPrinter struct {
+	template []templateNode
+}
+
+func (p *jsonPathPrinter) Print(w io.Writer, obj interface{}) error {
+	data, err := toGeneric(obj)
+	if err != nil {
+		return err
+	}
+
+	buf := &strings.Builder{}
+	if err := execTemplate(buf, p.template, data); err != nil {
+		return err
+	}
+
+	res := buf.String()
+	if !strings.HasSuffix(res, "\n") {
+		res += "\n"
+	}
+	_, err = io.WriteString(w, res)
+	return err
+}
+
+func execTemplate(w *strings.Builder, nodes []templateNode, data interface{}) error {
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case textNode:
+			w.WriteString(string(n))
+		case pathNode:
+			var values []string
+			for _, v := range n.eval(data) {
+				values = append(values, formatValue(v))
+			}
+			w.WriteString(strings.Join(values, " "))
+		case *rangeNode:
+			for _, item := range n.path.eval(data) {
+				if err := execTemplate(w, n.body, item); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p pathNode) eval(data interface{}) []interface{} {
+	cur := []interface{}{data}
+	for _, seg := range p {
+		var next []interface{}
+		for _, v := range cur {
+			next = append(next, seg.eval(v)...)
+		}
+		cur = next
+	}
+
+	return cur
+}
+
+func (s pathSegment) eval(v interface{}) []interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if s.all {
+			var keys []string
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			var res []interface{}
+			for _, k := range keys {
+				res = append(res, v[k])
+			}
+			return res
+		}
+		if s.isIdx {
+			return nil
+		}
+		if f, found := v[s.field]; found {
+			return []interface{}{f}
+		}
+	case []interface{}:
+		if s.all {
+			return v
+		}
+		if !s.isIdx {
+			return nil
+		}
+		idx := s.index
+		if idx < 0 {
+			idx += len(v)
+		}
+		if idx >= 0 && idx < len(v) {
+			return []interface{}{v[idx]}
+		}
+	}
+
+	return nil
+}
+
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+func parseTemplate(tmpl string) ([]templateNode, error) {
+	if tmpl == "" {
+		return nil, fmt.Errorf("empty jsonpath template")
+	}
+
+	root := &rangeNode{}
+	stack := []*rangeNode{root}
+	for len(tmpl) > 0 {
+		start := strings.Index(tmpl, "{")
+		if start < 0 {
+			top := stack[len(stack)-1]
+			top.body = append(top.body, textNode(tmpl))
+			break
+		}
+		if start > 0 {
+			top := stack[len(stack)-1]
+			top.body = append(top.body, textNode(tmpl[:start]))
+		}
+
+		end := matchBrace(tmpl, start)
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed action in jsonpath template %q", tmpl)
+		}
+		action := strings.TrimSpace(tmpl[start+1 : end])
+		tmpl = tmpl[end+1:]
+
+		top := stack[len(stack)-1]
+		switch {
+		case action == "end":
+			if len(stack) == 1 {
+				return nil, fmt.Errorf("unexpected {end} in jsonpath template")
+			}
+			stack = stack[:len(stack)-1]
+		case strings.HasPrefix(action, "range "):
+			path, err := parsePath(strings.TrimSpace(strings.TrimPrefix(action, "range ")))
+			if err != nil {
+				return nil, err
+			}
+			r := &rangeNode{path: path}
+			top.body = append(top.body, r)
+			stack = append(stack, r)
+		case strings.HasPrefix(action, "\""):
+			text, err := strconv.Unquote(action)
+			if err != nil {
+				return nil, fmt.Errorf("invalid string %s in jsonpath template: %v", action, err)
+			}
+			top.body = append(top.body, textNode(text))
+		default:
+			path, err := parsePath(action)
+			if err != nil {
+				return nil, err
+			}
+			top.body = append(top.body, path)
+		}
+	}
+
+	if len(stack) != 1 {
+		return nil, fmt.Errorf("missing {end} in jsonpath template")
+	}
+
+	return root.body, nil
+}
+
+// matchBrace returns the index of the "}" closing the "{" at start, skipping the quoted strings.
+func matchBrace(s string, start int) int {
+	inQuote := false
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if inQuote {
+				i++
+			}
+		case '"':
+			inQuote = !inQuote
+		case '}':
+			if !inQuote {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+func parsePath(path string) (pathNode, error) {
+	orig := path
+	path = strings.TrimPrefix(path, "$")
+
+	var res pathNode
+	for len(path) > 0 {
+		switch path[0] {
+		case '.':
+			path = path[1:]
+			end := strings.IndexAny(path, ".[")
+			if end < 0 {
+				end = len(path)
+			}
+			field := path[:end]
+			path = path[end:]
+			switch field {
+			case "":
+			case "*":
+				res = append(res, pathSegment{all: true})
+			default:
+				res = append(res, pathSegment{field: field})
+			}
+		case '[':
+			end := strings.Index(path, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed [ in path %q", orig)
+			}
+			idx := strings.TrimSpace(path[1:end])
+			path = path[end+1:]
+			if idx == "*" {
+				res = append(res, pathSegment{all: true})
+				continue
+			}
+			n, err := strconv.Atoi(idx)
+			if err != nil {
+				return nil, fmt.Errorf("invalid index %q in path %q", idx, orig)
+			}
+			res = append(res, pathSegment{index: n, isIdx: true})
+		default:
+			// The leading "." is optional, e.g. "name" is the same as ".name".
+			path = "." + path
+		}
+	}
+
+	return res, nil
+}
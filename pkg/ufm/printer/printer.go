@@ -0,0 +1,147 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package printer prints the objects of UFM, e.g. ufm.IBNetwork and ufm.IBPort, in
+// the output formats of the ufm command line: table, wide, json, yaml,
+// jsonpath=<template> and custom-columns=<header>:<path>,...; the ufm.Topology is also
+// printed as a graph in dot and graphml.
+package printer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+type Format string
+
+const (
+	TableFormat         Format = "table"
+	WideFormat          Format = "wide"
+	JSONFormat          Format = "json"
+	YAMLFormat          Format = "yaml"
+	JSONPathFormat      Format = "jsonpath"
+	CustomColumnsFormat Format = "custom-columns"
+	DOTFormat           Format = "dot"
+	GraphMLFormat       Format = "graphml"
+)
+
+// Formats is the help message of the supported output formats.
+const Formats = "table|wide|json|yaml|jsonpath=<template>|custom-columns=<header>:<path>,..."
+
+// GraphFormats is the help message of the output formats of the ufm.Topology.
+const GraphFormats = "table|wide|dot|graphml|json|yaml"
+
+// Printer prints an object, or a slice of objects, to the writer.
+type Printer interface {
+	Print(w io.Writer, obj interface{}) error
+}
+
+// NewPrinter creates the Printer of the output format, e.g. "json" or "jsonpath={[*].name}";
+// the table format is used if output is empty.
+func NewPrinter(output string) (Printer, error) {
+	format, arg := ParseOutput(output)
+	switch format {
+	case TableFormat:
+		return &tablePrinter{}, nil
+	case WideFormat:
+		return &tablePrinter{wide: true}, nil
+	case JSONFormat:
+		return &jsonPrinter{}, nil
+	case YAMLFormat:
+		return &yamlPrinter{}, nil
+	case JSONPathFormat:
+		tmpl, err := parseTemplate(arg)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonPathPrinter{template: tmpl}, nil
+	case CustomColumnsFormat:
+		columns, err := parseCustomColumns(arg)
+		if err != nil {
+			return nil, err
+		}
+		return &customColumnsPrinter{columns: columns}, nil
+	case DOTFormat:
+		return &dotPrinter{}, nil
+	case GraphMLFormat:
+		return &graphMLPrinter{}, nil
+	}
+
+	return nil, fmt.Errorf("unknown output format %q, one of %s", output, Formats)
+}
+
+// ParseOutput splits the output flag into the format and its argument, e.g. the template of jsonpath.
+func ParseOutput(output string) (Format, string) {
+	if output == "" {
+		return TableFormat, ""
+	}
+
+	parts := strings.SplitN(output, "=", 2)
+	if len(parts) == 1 {
+		return Format(parts[0]), ""
+	}
+
+	return Format(parts[0]), parts[1]
+}
+
+// IsTable returns true if the output is table or wide, which may be printed by the caller for
+// the objects without columns, e.g. the detail of an IB network.
+func IsTable(output string) bool {
+	format, _ := ParseOutput(output)
+	return format == TableFormat || format == WideFormat
+}
+
+type jsonPrinter struct{}
+
+func (p *jsonPrinter) Print(w io.Writer, obj interface{}) error {
+	data, err := json.MarshalIndent(obj, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+type yamlPrinter struct{}
+
+func (p *yamlPrinter) Print(w io.Writer, obj interface{}) error {
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// toGeneric converts the object into the generic JSON value, so the paths are
+// evaluated by the JSON field names.
+func toGeneric(obj interface{}) (interface{}, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	var res interface{}
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package printer_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/openbce/kperf/pkg/ufm"
+	"github.com/openbce/kperf/pkg/ufm/printer"
+)
+
+var testIBNetworks = []*ufm.IBNetwork{
+	{Name: "tenant-a", PKey: 0x100, GUIDs: []string{"0x1", "0x2"}, MTU: 4, RateLimit: 2.5},
+	{Name: "tenant-b", PKey: 0x200, EnableSharp: true},
+}
+
+func printString(t *testing.T, output string, obj interface{}) string {
+	t.Helper()
+
+	p, err := printer.NewPrinter(output)
+	if err != nil {
+		t.Fatalf("failed to create printer of %q: %v", output, err)
+	}
+	var buf bytes.Buffer
+	if err := p.Print(&buf, obj); err != nil {
+		t.Fatalf("failed to print %q: %v", output, err)
+	}
+	return buf.String()
+}
+
+func TestJSONPath(t *testing.T) {
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{template: "{[*].name}", expected: "tenant-a tenant-b\n"},
+		{template: "{$[*].pkey}", expected: "256 512\n"},
+		{template: "{[0].guids[1]}", expected: "0x2\n"},
+		{template: "{[0].guids[-1]}", expected: "0x2\n"},
+		{template: "{[0].guids[5]}", expected: "\n"},
+		{template: "{[1].guids[0]}", expected: "\n"},
+		{template: "{[0].unknown}", expected: "\n"},
+		{template: "{[*].enable_sharp}", expected: "false true\n"},
+		{template: "{[0].rate_limit}", expected: "2.5\n"},
+		{template: "{[0].guids}", expected: `["0x1","0x2"]` + "\n"},
+		{template: "pkey={[0].pkey}", expected: "pkey=256\n"},
+		{template: `{[0].name}{"}"}`, expected: "tenant-a}\n"},
+		{template: `{range [*]}{.name}{"\t"}{.pkey}{"\n"}{end}`, expected: "tenant-a\t256\ntenant-b\t512\n"},
+		{template: `{range [*]}{name}:{range .guids[*]}{.};{end}{"\n"}{end}`, expected: "tenant-a:0x1;0x2;\ntenant-b:\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			if res := printString(t, "jsonpath="+tt.template, testIBNetworks); res != tt.expected {
+				t.Errorf("got %q, expected %q", res, tt.expected)
+			}
+		})
+	}
+}
+
+func TestJSONPathErrors(t *testing.T) {
+	for _, tmpl := range []string{
+		"",
+		"{[*].name",
+		"{end}",
+		"{range [*]}{.name}",
+		"{[x].name}",
+		"{[0.name}",
+		`{"\q"}`,
+	} {
+		if _, err := printer.NewPrinter("jsonpath=" + tmpl); err == nil {
+			t.Errorf("expected error of jsonpath template %q", tmpl)
+		}
+	}
+}
+
+func TestCustomColumns(t *testing.T) {
+	res := printString(t, "custom-columns=NAME:.name,GUIDS:{.guids[*]},SHARP:enable_sharp", testIBNetworks)
+	expected := []string{
+		"NAME       GUIDS     SHARP",
+		"tenant-a   0x1,0x2   false",
+		"tenant-b   <none>    true",
+	}
+	if lines := strings.Split(strings.TrimSuffix(res, "\n"), "\n"); strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("got\n%s\nexpected\n%s", res, strings.Join(expected, "\n"))
+	}
+}
+
+func TestCustomColumnsErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"NAME",
+		":.name",
+		"NAME:.name,PKEY",
+		"NAME:[x]",
+	} {
+		if _, err := printer.NewPrinter("custom-columns=" + spec); err == nil {
+			t.Errorf("expected error of custom columns %q", spec)
+		}
+	}
+}
+
+func TestTableColumns(t *testing.T) {
+	tests := []struct {
+		output  string
+		headers []string
+	}{
+		{output: "", headers: []string{"NAME", "PKEY", "SHARP", "IPOIB", "MTU", "RATE", "LEVEL", "GUID#"}},
+		{output: "table", headers: []string{"NAME", "PKEY", "SHARP", "IPOIB", "MTU", "RATE", "LEVEL", "GUID#"}},
+		{output: "wide", headers: []string{"NAME", "PKEY", "SHARP", "IPOIB", "MTU", "RATE", "LEVEL", "GUID#", "INDEX0", "GUIDS"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.output, func(t *testing.T) {
+			lines := strings.Split(strings.TrimSuffix(printString(t, tt.output, testIBNetworks), "\n"), "\n")
+			if len(lines) != 3 {
+				t.Fatalf("got %d lines, expected the header and 2 rows", len(lines))
+			}
+			if headers := strings.Fields(lines[0]); strings.Join(headers, " ") != strings.Join(tt.headers, " ") {
+				t.Errorf("got headers %v, expected %v", headers, tt.headers)
+			}
+			if row := strings.Fields(lines[1]); len(row) != len(tt.headers) || row[0] != "tenant-a" || row[1] != "0x0100" {
+				t.Errorf("unexpected row %v", row)
+			}
+		})
+	}
+
+	// A single object is printed as a table of one row.
+	if lines := strings.Split(strings.TrimSuffix(printString(t, "", testIBNetworks[0]), "\n"), "\n"); len(lines) != 2 {
+		t.Errorf("got %d lines of a single object, expected 2", len(lines))
+	}
+}
+
+func TestPrinterErrors(t *testing.T) {
+	if _, err := printer.NewPrinter("xml"); err == nil {
+		t.Errorf("expected error of unknown output format")
+	}
+
+	p, err := printer.NewPrinter("table")
+	if err != nil {
+		t.Fatalf("failed to create table printer: %v", err)
+	}
+	if err := p.Print(&bytes.Buffer{}, struct{ Name string }{}); err == nil {
+		t.Errorf("expected error of the object without columns")
+	}
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package printer
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// Column is a column of the objects in the table format.
+type Column struct {
+	Header string
+	// The column is only printed in the wide format.
+	Wide bool
+	// Value returns the value of the column of the object.
+	Value func(obj interface{}) string
+}
+
+var (
+	columnsMutex sync.RWMutex
+	columns      = map[reflect.Type][]Column{}
+)
+
+// RegisterColumns registers the columns of the object type in the table format, e.g.
+// RegisterColumns(&ufm.IBNetwork{}, ...); the object is passed to Column.Value as is.
+func RegisterColumns(obj interface{}, cols []Column) {
+	columnsMutex.Lock()
+	defer columnsMutex.Unlock()
+
+	columns[reflect.TypeOf(obj)] = cols
+}
+
+func lookupColumns(t reflect.Type) ([]Column, bool) {
+	columnsMutex.RLock()
+	defer columnsMutex.RUnlock()
+
+	cols, found := columns[t]
+	return cols, found
+}
+
+type tablePrinter struct {
+	wide bool
+}
+
+func (p *tablePrinter) Print(w io.Writer, obj interface{}) error {
+	items, itemType := toItems(obj)
+	cols, found := lookupColumns(itemType)
+	if !found {
+		return fmt.Errorf("no table columns of %v, try json or yaml", itemType)
+	}
+
+	var headers []string
+	for _, c := range cols {
+		if !c.Wide || p.wide {
+			headers = append(headers, c.Header)
+		}
+	}
+
+	tw := newTabWriter(w)
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	for _, item := range items {
+		var values []string
+		for _, c := range cols {
+			if !c.Wide || p.wide {
+				values = append(values, c.Value(item))
+			}
+		}
+		fmt.Fprintln(tw, strings.Join(values, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+type customColumn struct {
+	header string
+	path   pathNode
+}
+
+type customColumnsPrinter struct {
+	columns []customColumn
+}
+
+func (p *customColumnsPrinter) Print(w io.Writer, obj interface{}) error {
+	items, _ := toItems(obj)
+
+	tw := newTabWriter(w)
+	var headers []string
+	for _, c := range p.columns {
+		headers = append(headers, c.header)
+	}
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+
+	for _, item := range items {
+		data, err := toGeneric(item)
+		if err != nil {
+			return err
+		}
+		var values []string
+		for _, c := range p.columns {
+			var res []string
+			for _, v := range c.path.eval(data) {
+				res = append(res, formatValue(v))
+			}
+			if len(res) == 0 {
+				res = []string{"<none>"}
+			}
+			values = append(values, strings.Join(res, ","))
+		}
+		fmt.Fprintln(tw, strings.Join(values, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+// parseCustomColumns parses the spec of custom columns, e.g. "NAME:.name,PKEY:.pkey".
+func parseCustomColumns(spec string) ([]customColumn, error) {
+	if spec == "" {
+		return nil, fmt.Errorf("empty custom columns")
+	}
+
+	var res []customColumn
+	for _, col := range strings.Split(spec, ",") {
+		parts := strings.SplitN(col, ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid custom column %q, expected <header>:<path>", col)
+		}
+		path := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(parts[1]), "{"), "}")
+		p, err := parsePath(path)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, customColumn{header: parts[0], path: p})
+	}
+
+	return res, nil
+}
+
+// toItems returns the items of a slice, or the object itself; the type of the items is also returned.
+func toItems(obj interface{}) ([]interface{}, reflect.Type) {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Slice {
+		return []interface{}{obj}, v.Type()
+	}
+
+	var items []interface{}
+	for i := 0; i < v.Len(); i++ {
+		items = append(items, v.Index(i).Interface())
+	}
+
+	return items, v.Type().Elem()
+}
+
+func newTabWriter(w io.Writer) *tabwriter.Writer {
+	return tabwriter.NewWriter(w, 0, 8, 3, ' ', 0)
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy defines how the ufmclient retries the failed requests. Only idempotent
+// requests (GET, PUT and DELETE) are retried automatically; a POST is retried only
+// if its context is marked by WithIdempotent.
+type RetryPolicy struct {
+	// The max attempts of a request including the first one; 1 means no retry.
+	MaxAttempts int
+	// The backoff before the first retry.
+	InitialBackoff time.Duration
+	// The upper bound of the backoff.
+	MaxBackoff time.Duration
+	// The factor to grow the backoff after each retry.
+	Multiplier float64
+	// The randomization factor of the backoff, range from 0 to 1.
+	Jitter float64
+	// The HTTP status codes which are retried.
+	RetryableStatusCodes []int
+	// Whether to retry network errors, e.g. connection refused/reset or timeout.
+	RetryNetworkErrors bool
+}
+
+// DefaultRetryPolicy returns the retry policy used by the ufmclient if not specified.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+		RetryableStatusCodes: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+		RetryNetworkErrors: true,
+	}
+}
+
+// NoRetryPolicy returns a retry policy which never retries.
+func NoRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 1}
+}
+
+func (p *RetryPolicy) attempts(ctx context.Context, method string) int {
+	if p.MaxAttempts <= 1 {
+		return 1
+	}
+
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return p.MaxAttempts
+	}
+
+	if isIdempotent(ctx) {
+		return p.MaxAttempts
+	}
+
+	return 1
+}
+
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	d := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+
+	if p.Jitter > 0 {
+		/* #nosec */
+		d += d * p.Jitter * (rand.Float64()*2 - 1)
+	}
+
+	return time.Duration(d)
+}
+
+func (p *RetryPolicy) isRetryableStatus(code int) bool {
+	for _, c := range p.RetryableStatusCodes {
+		if c == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *RetryPolicy) isRetryableError(err error) bool {
+	if !p.RetryNetworkErrors {
+		return false
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// parseRetryAfter returns the delay in the Retry-After header, only delay-seconds is supported.
+func parseRetryAfter(header http.Header) time.Duration {
+	if header == nil {
+		return 0
+	}
+
+	secs, err := strconv.Atoi(header.Get("Retry-After"))
+	if err != nil || secs <= 0 {
+		return 0
+	}
+
+	return time.Duration(secs) * time.Second
+}
+
+type idempotentKey struct{}
+
+// WithIdempotent marks the requests with the returned context as idempotent, so
+// the ufmclient retries them according to its RetryPolicy even if they're POST.
+func WithIdempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+func isIdempotent(ctx context.Context) bool {
+	v, ok := ctx.Value(idempotentKey{}).(bool)
+	return ok && v
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// rollbackTimeout bounds the rollback of a saga, which runs even if the context of the
+// saga is done, e.g. timeout.
+const rollbackTimeout = time.Minute
+
+// sagaStep is a step of a saga, with the undo of its changes.
+type sagaStep struct {
+	name string
+	do   func(ctx context.Context) *UFMError
+	// undo is nil if the step changes nothing to roll back.
+	undo func(ctx context.Context) *UFMError
+}
+
+// SagaError reports a failed multi-step operation, e.g. CreateIBNetwork: which steps
+// succeeded, which one failed, and which were rolled back; it's the Err of the UFMError.
+type SagaError struct {
+	Succeeded  []string
+	Failed     string
+	RolledBack []string
+	// The steps failed to roll back; the changes of them are left in UFM.
+	RollbackFailed []RollbackFailure
+	// The error of the failed step.
+	Err *UFMError
+}
+
+// RollbackFailure is a step of saga failed to roll back.
+type RollbackFailure struct {
+	Step string
+	Err  *UFMError
+}
+
+func (e *SagaError) Error() string {
+	msg := fmt.Sprintf("step %s failed: %v; succeeded: [%s], rolled back: [%s]",
+		e.Failed, e.Err, strings.Join(e.Succeeded, ","), strings.Join(e.RolledBack, ","))
+	for _, f := range e.RollbackFailed {
+		msg += fmt.Sprintf("; failed to roll back %s: %v", f.Step, f.Err)
+	}
+	return msg
+}
+
+func (e *SagaError) Unwrap() error {
+	return e.Err
+}
+
+// runSaga runs the steps in order; if one fails, the succeeded ones are undone in reverse order.
+// The failed step is undone as well if its job in UFM failed, as its changes may be partly done.
+func runSaga(ctx context.Context, steps []sagaStep) *SagaError {
+	var done []sagaStep
+	for _, step := range steps {
+		if ufmErr := step.do(ctx); ufmErr != nil {
+			sagaErr := &SagaError{Failed: step.name, Err: ufmErr}
+			for _, s := range done {
+				sagaErr.Succeeded = append(sagaErr.Succeeded, s.name)
+			}
+			var jobErr *JobError
+			if errors.As(ufmErr, &jobErr) {
+				done = append(done, step)
+			}
+			rollback(sagaErr, done)
+			return sagaErr
+		}
+		done = append(done, step)
+	}
+
+	return nil
+}
+
+func rollback(sagaErr *SagaError, done []sagaStep) {
+	// The context of the saga may be done, e.g. timeout; roll back by a new one.
+	ctx, cancel := context.WithTimeout(context.Background(), rollbackTimeout)
+	defer cancel()
+
+	for i := len(done) - 1; i >= 0; i-- {
+		step := done[i]
+		if step.undo == nil {
+			continue
+		}
+		if ufmErr := step.undo(ctx); ufmErr != nil {
+			sagaErr.RollbackFailed = append(sagaErr.RollbackFailed, RollbackFailure{Step: step.name, Err: ufmErr})
+			continue
+		}
+		sagaErr.RolledBack = append(sagaErr.RolledBack, step.name)
+	}
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufm_test
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/openbce/kperf/pkg/ufm"
+	"github.com/openbce/kperf/pkg/ufm/ufmtest"
+)
+
+var (
+	addGUIDsFault    = ufmtest.Fault{Method: http.MethodPost, Path: "/ufmRest/resources/pkeys", StatusCode: http.StatusBadRequest}
+	updateQoSFault   = ufmtest.Fault{Method: http.MethodPut, Path: "/ufmRest/resources/pkeys/qos_conf", StatusCode: http.StatusBadRequest}
+	createSharpFault = ufmtest.Fault{Method: http.MethodPost, Path: "/ufmRest/app/sharp/reservations", StatusCode: http.StatusBadRequest}
+)
+
+// sagaError returns the SagaError wrapped by the error of a multi-step operation.
+func sagaError(t *testing.T, err *ufm.UFMError) *ufm.SagaError {
+	t.Helper()
+
+	if err == nil {
+		t.Fatalf("expected error of the failed step")
+	}
+	var sagaErr *ufm.SagaError
+	if !errors.As(err, &sagaErr) {
+		t.Fatalf("error %v does not wrap SagaError", err)
+	}
+
+	return sagaErr
+}
+
+func checkSteps(t *testing.T, sagaErr *ufm.SagaError, failed string, succeeded, rolledBack []string) {
+	t.Helper()
+
+	if sagaErr.Failed != failed {
+		t.Errorf("failed step is %q, expected %q", sagaErr.Failed, failed)
+	}
+	if !reflect.DeepEqual(sagaErr.Succeeded, succeeded) {
+		t.Errorf("succeeded steps are %v, expected %v", sagaErr.Succeeded, succeeded)
+	}
+	if !reflect.DeepEqual(sagaErr.RolledBack, rolledBack) {
+		t.Errorf("rolled back steps are %v, expected %v", sagaErr.RolledBack, rolledBack)
+	}
+	if len(sagaErr.RollbackFailed) != 0 {
+		t.Errorf("unexpected rollback failures %v", sagaErr.RollbackFailed)
+	}
+}
+
+func TestCreateRollback(t *testing.T) {
+	tests := []struct {
+		name       string
+		fault      ufmtest.Fault
+		failed     string
+		succeeded  []string
+		rolledBack []string
+	}{
+		{name: "add guids", fault: addGUIDsFault, failed: "add-guids"},
+		{name: "update qos", fault: updateQoSFault, failed: "update-qos", succeeded: []string{"add-guids"}, rolledBack: []string{"add-guids"}},
+		// The QoS of the created pkey is deleted with the pkey, so update-qos has nothing to roll back.
+		{name: "create sharp", fault: createSharpFault, failed: "create-sharp", succeeded: []string{"add-guids", "update-qos"}, rolledBack: []string{"add-guids"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, srv := newUFM(t, "")
+			srv.AddFault(tt.fault)
+
+			err := u.CreateIBNetwork(&ufm.IBNetwork{PKey: 0x100, GUIDs: []string{guid1, guid2}, EnableSharp: true, RateLimit: 10})
+			checkSteps(t, sagaError(t, err), tt.failed, tt.succeeded, tt.rolledBack)
+
+			if _, found := srv.GetIBNetwork(0x100); found {
+				t.Errorf("pkey 0x100 is not deleted by the rollback")
+			}
+			if _, found := srv.SharpReservation(0x100); found {
+				t.Errorf("SHARP reservation of pkey 0x100 is not deleted by the rollback")
+			}
+		})
+	}
+}
+
+func TestUpdateRollback(t *testing.T) {
+	tests := []struct {
+		name       string
+		fault      ufmtest.Fault
+		failed     string
+		succeeded  []string
+		rolledBack []string
+	}{
+		{name: "add guids", fault: addGUIDsFault, failed: "add-guids"},
+		{name: "update qos", fault: updateQoSFault, failed: "update-qos", succeeded: []string{"add-guids"}, rolledBack: []string{"add-guids"}},
+		{name: "create sharp", fault: createSharpFault, failed: "create-sharp", succeeded: []string{"add-guids", "update-qos"}, rolledBack: []string{"update-qos", "add-guids"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, srv := newUFM(t, "")
+			srv.AddIBNetwork(&ufm.IBNetwork{PKey: 0x100, GUIDs: []string{guid1}, ServiceLevel: 1, RateLimit: 2.5})
+			before, _ := srv.GetIBNetwork(0x100)
+			srv.AddFault(tt.fault)
+
+			err := u.CreateIBNetwork(&ufm.IBNetwork{PKey: 0x100, GUIDs: []string{guid1, guid2}, EnableSharp: true, ServiceLevel: 3, RateLimit: 10})
+			checkSteps(t, sagaError(t, err), tt.failed, tt.succeeded, tt.rolledBack)
+
+			// Only the changes of the call are rolled back; the existing pkey is kept.
+			after, found := srv.GetIBNetwork(0x100)
+			if !found {
+				t.Fatalf("the existing pkey 0x100 is deleted by the rollback")
+			}
+			if !reflect.DeepEqual(after, before) {
+				t.Errorf("pkey 0x100 is %+v after the rollback, expected %+v", after, before)
+			}
+		})
+	}
+}
+
+func TestAllocateRollback(t *testing.T) {
+	u, srv := newUFM(t, "")
+	srv.AddFault(updateQoSFault)
+
+	ib := &ufm.IBNetwork{GUIDs: []string{guid1}}
+	err := u.CreateIBNetwork(ib)
+	checkSteps(t, sagaError(t, err), "update-qos", []string{"allocate-pkey"}, []string{"allocate-pkey"})
+
+	if pkeys := srv.PKeys(); !reflect.DeepEqual(pkeys, []int32{ufm.DefaultPKey}) {
+		t.Errorf("pkeys are %v after the rollback, expected only the default pkey", pkeys)
+	}
+}
+
+func TestJobFailedRollback(t *testing.T) {
+	u, srv := newUFM(t, "")
+	srv.SetAsyncGUIDs(1)
+	srv.FailJobs("failed to add GUIDs")
+	// Only the job of adding GUIDs fails, so the rollback succeeds.
+	srv.AfterRequest(http.MethodPost, "/ufmRest/resources/pkeys", func(ufmtest.Request) { srv.FailJobs("") })
+
+	err := u.CreateIBNetwork(&ufm.IBNetwork{PKey: 0x100, GUIDs: []string{guid1}})
+	// The failed job may have changed UFM partly, so its step is rolled back as well.
+	checkSteps(t, sagaError(t, err), "add-guids", nil, []string{"add-guids"})
+
+	if _, found := srv.GetIBNetwork(0x100); found {
+		t.Errorf("pkey 0x100 is not deleted by the rollback")
+	}
+}
+
+func TestRollbackFailed(t *testing.T) {
+	u, srv := newUFM(t, "")
+	srv.AddFault(updateQoSFault)
+	srv.AddFault(ufmtest.Fault{Method: http.MethodDelete, Path: "/ufmRest/resources/pkeys", StatusCode: http.StatusBadRequest})
+
+	sagaErr := sagaError(t, u.CreateIBNetwork(&ufm.IBNetwork{PKey: 0x100, GUIDs: []string{guid1}}))
+	if len(sagaErr.RolledBack) != 0 {
+		t.Errorf("unexpected rolled back steps %v", sagaErr.RolledBack)
+	}
+	if len(sagaErr.RollbackFailed) != 1 || sagaErr.RollbackFailed[0].Step != "add-guids" {
+		t.Errorf("rollback failures are %+v, expected add-guids", sagaErr.RollbackFailed)
+	}
+	if _, found := srv.GetIBNetwork(0x100); !found {
+		t.Errorf("pkey 0x100 is deleted although its rollback failed")
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// The SHARP reservations of UFM allocate the SHARP (Scalable Hierarchical Aggregation and
+// Reduction Protocol) resources to the GUIDs of a pkey; all the requests of SHARP use the
+// paths below.
+const (
+	sharpReservationsPath = "/ufmRest/app/sharp/reservations"
+	sharpReservationPath  = "/ufmRest/app/sharp/reservations/0x%x"
+)
+
+type sharpReservation struct {
+	PKey  string   `json:"pkey"`
+	GUIDs []string `json:"guids"`
+}
+
+// listSharpReservations returns the pkeys which have a SHARP reservation; it's empty if SHARP
+// is not enabled in UFM.
+func (u *UFM) listSharpReservations(ctx context.Context) (map[int32]bool, *UFMError) {
+	data, err := u.client.GetWithContext(ctx, u.buildURL(sharpReservationsPath))
+	if err != nil {
+		if err.IsNotFound() {
+			return map[int32]bool{}, nil
+		}
+		return nil, wrapError(err, "failed to list SHARP reservations")
+	}
+
+	var reservations []sharpReservation
+	if err := json.Unmarshal(data, &reservations); err != nil {
+		return nil, &UFMError{
+			Code:    UnknownErr,
+			Message: fmt.Sprintf("failed to unmarshal SHARP reservations with error: %v", err),
+			Err:     err,
+		}
+	}
+
+	res := map[int32]bool{}
+	for _, r := range reservations {
+		if pkey, err := ParsePkey(r.PKey); err == nil {
+			res[pkey] = true
+		}
+	}
+
+	return res, nil
+}
+
+// hasSharpReservation returns true if the pkey has a SHARP reservation; it's false if SHARP is
+// not enabled in UFM, or the response is not a reservation of the pkey, e.g. {}.
+func (u *UFM) hasSharpReservation(ctx context.Context, pkey int32) (bool, *UFMError) {
+	data, err := u.client.GetWithContext(ctx, u.buildURL(fmt.Sprintf(sharpReservationPath, pkey)))
+	if err != nil {
+		if err.IsNotFound() {
+			return false, nil
+		}
+		return false, wrapError(err, "failed to get SHARP reservation of PKey 0x%04X", pkey)
+	}
+
+	reservation := &sharpReservation{}
+	if err := json.Unmarshal(data, reservation); err != nil {
+		return false, &UFMError{
+			Code:    UnknownErr,
+			Message: fmt.Sprintf("failed to unmarshal SHARP reservation of PKey 0x%04X with error: %v", pkey, err),
+			Err:     err,
+		}
+	}
+	reserved, parseErr := ParsePkey(reservation.PKey)
+
+	return parseErr == nil && reserved == pkey, nil
+}
+
+func (u *UFM) createSharpReservation(ctx context.Context, ib *IBNetwork) *UFMError {
+	pkey, _ := BuidPKey(ib.PKey)
+
+	data, err := json.Marshal(&sharpReservation{PKey: pkey, GUIDs: memberGUIDs(ib.GUIDMembers())})
+	if err != nil {
+		return &UFMError{
+			Code:    UnknownErr,
+			Message: fmt.Sprintf("failed to marshal SHARP reservation with error: %v", err),
+			Err:     err,
+		}
+	}
+
+	if _, err := u.client.PostWithContext(ctx, u.buildURL(sharpReservationsPath), data); err != nil {
+		return wrapError(err, "failed to create SHARP reservation of PKey 0x%04X", ib.PKey)
+	}
+
+	return nil
+}
+
+// deleteSharpReservation deletes the SHARP reservation of the pkey; it's not an error if the
+// pkey has no reservation.
+func (u *UFM) deleteSharpReservation(ctx context.Context, pkey int32) *UFMError {
+	if _, err := u.client.DeleteWithContext(ctx, u.buildURL(fmt.Sprintf(sharpReservationPath, pkey))); err != nil {
+		if err.IsNotFound() {
+			return nil
+		}
+		return wrapError(err, "failed to delete SHARP reservation of PKey 0x%04X", pkey)
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufm_test
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openbce/kperf/pkg/ufm"
+	"github.com/openbce/kperf/pkg/ufm/ufmtest"
+)
+
+const sharpPath = "/ufmRest/app/sharp/reservations"
+
+func TestSharpReservation(t *testing.T) {
+	u, srv := newUFM(t, "")
+
+	ib := &ufm.IBNetwork{Name: "tenant-a", PKey: 0x100, GUIDs: []string{guid1, guid2}, EnableSharp: true}
+	if err := u.CreateIBNetwork(ib); err != nil {
+		t.Fatalf("failed to create IB network: %v", err)
+	}
+	guids, found := srv.SharpReservation(0x100)
+	if !found || !reflect.DeepEqual(guids, []string{guid1, guid2}) {
+		t.Fatalf("SHARP reservation is %v (%t), expected %v", guids, found, []string{guid1, guid2})
+	}
+
+	got, err := u.GetIBNetwork(0x100)
+	if err != nil {
+		t.Fatalf("failed to get IB network: %v", err)
+	}
+	if !got.EnableSharp {
+		t.Errorf("SHARP is not enabled in the got IB network")
+	}
+
+	list, err := u.ListIBNetwork()
+	if err != nil {
+		t.Fatalf("failed to list IB networks: %v", err)
+	}
+	for _, n := range list {
+		if n.EnableSharp != (n.PKey == 0x100) {
+			t.Errorf("SHARP of pkey 0x%x is %t in the list", n.PKey, n.EnableSharp)
+		}
+	}
+
+	if err := u.DeleteIBNetwork(0x100); err != nil {
+		t.Fatalf("failed to delete IB network: %v", err)
+	}
+	if _, found := srv.SharpReservation(0x100); found {
+		t.Errorf("SHARP reservation is not deleted with the pkey")
+	}
+}
+
+func TestDeleteWithoutSharpReservation(t *testing.T) {
+	u, srv := newUFM(t, "")
+	srv.AddIBNetwork(&ufm.IBNetwork{PKey: 0x100, GUIDs: []string{guid1}})
+
+	if err := u.DeleteIBNetwork(0x100); err != nil {
+		t.Fatalf("failed to delete IB network: %v", err)
+	}
+	if n := countRequests(srv, http.MethodDelete, sharpPath+"/0x100"); n != 0 {
+		t.Errorf("deleted the SHARP reservation %d times, expected none", n)
+	}
+}
+
+// TestWithoutSharp runs against a UFM without the SHARP plugin, which returns 404 for SHARP.
+func TestWithoutSharp(t *testing.T) {
+	u, srv := newUFM(t, "")
+	srv.AddIBNetwork(&ufm.IBNetwork{PKey: 0x100, GUIDs: []string{guid1}})
+	srv.AddFault(ufmtest.Fault{Path: sharpPath, StatusCode: http.StatusNotFound})
+
+	list, err := u.ListIBNetwork()
+	if err != nil {
+		t.Fatalf("failed to list IB networks: %v", err)
+	}
+	if len(list) != 2 {
+		t.Errorf("listed %d IB networks, expected 2", len(list))
+	}
+
+	got, err := u.GetIBNetwork(0x100)
+	if err != nil {
+		t.Fatalf("failed to get IB network: %v", err)
+	}
+	if got.EnableSharp {
+		t.Errorf("SHARP is enabled without the SHARP plugin")
+	}
+
+	if err := u.DeleteIBNetwork(0x100); err != nil {
+		t.Fatalf("failed to delete IB network: %v", err)
+	}
+	if _, found := srv.GetIBNetwork(0x100); found {
+		t.Errorf("pkey 0x100 is not deleted")
+	}
+}
+
+func TestSharpReservationOfOtherPKey(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "empty", body: "{}"},
+		{name: "other pkey", body: `{"pkey": "0x200", "guids": []}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, srv := newUFM(t, "")
+			srv.AddIBNetwork(&ufm.IBNetwork{PKey: 0x100, GUIDs: []string{guid1}})
+			srv.AddFault(ufmtest.Fault{Method: http.MethodGet, Path: sharpPath + "/0x100", StatusCode: http.StatusOK, Body: tt.body})
+
+			got, err := u.GetIBNetwork(0x100)
+			if err != nil {
+				t.Fatalf("failed to get IB network: %v", err)
+			}
+			if got.EnableSharp {
+				t.Errorf("SHARP is enabled by the response %s", tt.body)
+			}
+		})
+	}
+}
+
+func TestCreateWithoutSharpLookups(t *testing.T) {
+	u, srv := newUFM(t, "")
+
+	if err := u.CreateIBNetwork(&ufm.IBNetwork{Name: "tenant-a", GUIDs: []string{guid1}}); err != nil {
+		t.Fatalf("failed to create IB network: %v", err)
+	}
+	for _, r := range srv.Requests() {
+		if r.Method == http.MethodGet && strings.HasPrefix(r.Path, sharpPath) {
+			t.Errorf("unexpected SHARP request %s %s of the allocation", r.Method, r.Path)
+		}
+	}
+}
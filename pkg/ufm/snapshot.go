@@ -0,0 +1,196 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SnapshotVersion is the version of the Snapshot format written by Export.
+const SnapshotVersion = "v1"
+
+// Snapshot is the configuration of all the partitions in UFM, e.g. for backup before upgrade.
+type Snapshot struct {
+	Version    string       `json:"version"`
+	CreatedAt  time.Time    `json:"created_at"`
+	UFMVersion string       `json:"ufm_version,omitempty"`
+	Partitions []*IBNetwork `json:"partitions"`
+}
+
+// RestoreOptions controls how a Snapshot is restored.
+type RestoreOptions struct {
+	// Delete the partitions in UFM which are not in the snapshot; DefaultPKey is never deleted.
+	Prune bool
+	// Only plan the changes without applying them.
+	DryRun bool
+}
+
+type RestoreAction string
+
+const (
+	RestoreCreated   RestoreAction = "created"
+	RestoreUpdated   RestoreAction = "updated"
+	RestoreUnchanged RestoreAction = "unchanged"
+	RestorePruned    RestoreAction = "pruned"
+)
+
+// RestoreSummary is the result of restoring a partition.
+type RestoreSummary struct {
+	PKey   int32         `json:"pkey"`
+	Name   string        `json:"name"`
+	Action RestoreAction `json:"action"`
+	Steps  []ApplyStep   `json:"steps,omitempty"`
+	// The error of the partition; the other partitions are still restored.
+	Err *UFMError `json:"-"`
+}
+
+func (u *UFM) Export() (*Snapshot, *UFMError) {
+	return u.ExportWithContext(context.Background())
+}
+
+// ExportWithContext returns the snapshot of all the partitions in UFM ordered by pkey.
+func (u *UFM) ExportWithContext(ctx context.Context) (*Snapshot, *UFMError) {
+	ver, ufmErr := u.VersionWithContext(ctx)
+	if ufmErr != nil {
+		return nil, wrapError(ufmErr, "failed to get version of UFM")
+	}
+
+	ibs, ufmErr := u.ListIBNetworkWithContext(ctx)
+	if ufmErr != nil {
+		return nil, ufmErr
+	}
+	sort.Slice(ibs, func(i, j int) bool { return ibs[i].PKey < ibs[j].PKey })
+
+	return &Snapshot{
+		Version:    SnapshotVersion,
+		CreatedAt:  time.Now().UTC(),
+		UFMVersion: ver,
+		Partitions: ibs,
+	}, nil
+}
+
+func (u *UFM) Restore(snapshot *Snapshot, opts RestoreOptions, jobOpts ...JobOption) ([]*RestoreSummary, *UFMError) {
+	return u.RestoreWithContext(context.Background(), snapshot, opts, jobOpts...)
+}
+
+// RestoreWithContext applies the partitions of the snapshot to UFM, and returns the summary of
+// each partition; the failed partitions do not stop the others, and an error is returned
+// after all the partitions are restored. The GUIDs of DefaultPKey are only added or updated,
+// as removing the hosts joined after the export cuts them off from the fabric. The jobs of UFM
+// are waited unless WithWait(false).
+func (u *UFM) RestoreWithContext(ctx context.Context, snapshot *Snapshot, opts RestoreOptions, jobOpts ...JobOption) ([]*RestoreSummary, *UFMError) {
+	if snapshot.Version != SnapshotVersion {
+		return nil, &UFMError{
+			Code:    InvalidConfigErr,
+			Message: fmt.Sprintf("unsupported snapshot version %q, expected %q", snapshot.Version, SnapshotVersion),
+		}
+	}
+
+	var res []*RestoreSummary
+	failed, pruned, pruneFailed := 0, 0, 0
+	desired := map[int32]bool{}
+	for _, ib := range snapshot.Partitions {
+		desired[ib.PKey] = true
+
+		summary := &RestoreSummary{PKey: ib.PKey, Name: ib.Name}
+		res = append(res, summary)
+
+		plan, ufmErr := u.PlanWithContext(ctx, ib)
+		if ufmErr != nil {
+			summary.Err = ufmErr
+			failed++
+			continue
+		}
+		if ib.PKey == DefaultPKey {
+			plan.Steps = additiveSteps(plan.Steps)
+		}
+		summary.Steps = plan.Steps
+		switch {
+		case plan.IsEmpty():
+			summary.Action = RestoreUnchanged
+		case plan.Current == nil:
+			summary.Action = RestoreCreated
+		default:
+			summary.Action = RestoreUpdated
+		}
+
+		if opts.DryRun || plan.IsEmpty() {
+			continue
+		}
+		if ufmErr := u.ApplyWithContext(ctx, plan, jobOpts...); ufmErr != nil {
+			summary.Err = ufmErr
+			failed++
+		}
+	}
+
+	if opts.Prune {
+		ibs, ufmErr := u.ListIBNetworkWithContext(ctx)
+		if ufmErr != nil {
+			return res, ufmErr
+		}
+		sort.Slice(ibs, func(i, j int) bool { return ibs[i].PKey < ibs[j].PKey })
+
+		for _, ib := range ibs {
+			if desired[ib.PKey] || ib.PKey == DefaultPKey {
+				continue
+			}
+
+			summary := &RestoreSummary{PKey: ib.PKey, Name: ib.Name, Action: RestorePruned}
+			res = append(res, summary)
+			pruned++
+			if opts.DryRun {
+				continue
+			}
+			if ufmErr := u.DeleteIBNetworkWithContext(ctx, ib.PKey, jobOpts...); ufmErr != nil {
+				summary.Err = ufmErr
+				pruneFailed++
+			}
+		}
+	}
+
+	var msgs []string
+	if failed != 0 {
+		msgs = append(msgs, fmt.Sprintf("failed to restore %d of %d partitions", failed, len(snapshot.Partitions)))
+	}
+	if pruneFailed != 0 {
+		msgs = append(msgs, fmt.Sprintf("failed to prune %d of %d partitions", pruneFailed, pruned))
+	}
+	if len(msgs) != 0 {
+		return res, &UFMError{
+			Code:    UnknownErr,
+			Message: strings.Join(msgs, "; "),
+		}
+	}
+
+	return res, nil
+}
+
+// additiveSteps returns the steps which neither remove GUIDs nor delete the SHARP reservation.
+func additiveSteps(steps []ApplyStep) []ApplyStep {
+	var res []ApplyStep
+	for _, s := range steps {
+		if s.Action == RemoveGUIDsAction || s.Action == DeleteSharpAction {
+			continue
+		}
+		res = append(res, s)
+	}
+	return res
+}
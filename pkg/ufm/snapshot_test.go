@@ -0,0 +1,107 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufm_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/openbce/kperf/pkg/ufm"
+	"github.com/openbce/kperf/pkg/ufm/ufmtest"
+)
+
+func TestExportAndRestore(t *testing.T) {
+	u, srv := newUFM(t, "")
+	srv.AddIBNetwork(&ufm.IBNetwork{Name: "tenant-a", PKey: 0x100, GUIDs: []string{guid1}, MTU: 4, RateLimit: 2.5})
+
+	snapshot, err := u.Export()
+	if err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	if len(snapshot.Partitions) != 2 {
+		t.Fatalf("exported %d partitions, expected 2", len(snapshot.Partitions))
+	}
+
+	if err := u.DeleteIBNetwork(0x100); err != nil {
+		t.Fatalf("failed to delete IB network: %v", err)
+	}
+	srv.AddIBNetwork(&ufm.IBNetwork{PKey: 0x200})
+
+	res, err := u.Restore(snapshot, ufm.RestoreOptions{Prune: true})
+	if err != nil {
+		t.Fatalf("failed to restore: %v", err)
+	}
+	actions := map[int32]ufm.RestoreAction{}
+	for _, s := range res {
+		actions[s.PKey] = s.Action
+	}
+	if actions[0x100] != ufm.RestoreCreated || actions[0x200] != ufm.RestorePruned || actions[ufm.DefaultPKey] != ufm.RestoreUnchanged {
+		t.Errorf("unexpected restore actions %v", actions)
+	}
+	if ib, found := srv.GetIBNetwork(0x100); !found || ib.Name != "tenant-a" || len(ib.GUIDs) != 1 {
+		t.Errorf("pkey 0x100 is not restored: %+v", ib)
+	}
+	if _, found := srv.GetIBNetwork(0x200); found {
+		t.Errorf("pkey 0x200 is not pruned")
+	}
+}
+
+func TestRestoreFailedWithPrune(t *testing.T) {
+	u, srv := newUFM(t, "")
+	srv.AddIBNetwork(&ufm.IBNetwork{PKey: 0x200})
+	srv.AddIBNetwork(&ufm.IBNetwork{PKey: 0x300})
+	srv.AddFault(ufmtest.Fault{Method: http.MethodPost, Path: "/ufmRest/resources/pkeys", StatusCode: http.StatusBadRequest})
+
+	snapshot := &ufm.Snapshot{
+		Version:    ufm.SnapshotVersion,
+		Partitions: []*ufm.IBNetwork{{Name: "tenant-a", PKey: 0x100, GUIDs: []string{guid1}}},
+	}
+	res, err := u.Restore(snapshot, ufm.RestoreOptions{Prune: true})
+	if err == nil {
+		t.Fatalf("expected error of the failed partition")
+	}
+	if err.Message != "failed to restore 1 of 1 partitions" {
+		t.Errorf("unexpected error %q", err.Message)
+	}
+	if len(res) != 3 {
+		t.Errorf("got %d summaries, expected 3", len(res))
+	}
+}
+
+func TestRestoreKeepsGUIDsOfDefaultPKey(t *testing.T) {
+	u, srv := newUFM(t, "")
+	srv.AddIBNetwork(&ufm.IBNetwork{Name: "management", PKey: ufm.DefaultPKey, GUIDs: []string{guid1}, MTU: 2, RateLimit: 2.5})
+
+	snapshot, err := u.Export()
+	if err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+
+	// A host joins the default pkey after the export.
+	srv.AddIBNetwork(&ufm.IBNetwork{Name: "management", PKey: ufm.DefaultPKey, GUIDs: []string{guid1, guid2}, MTU: 2, RateLimit: 2.5})
+
+	res, err := u.Restore(snapshot, ufm.RestoreOptions{})
+	if err != nil {
+		t.Fatalf("failed to restore: %v", err)
+	}
+	if len(res) != 1 || res[0].Action != ufm.RestoreUnchanged {
+		t.Errorf("unexpected restore summaries %+v", res)
+	}
+	if ib, _ := srv.GetIBNetwork(ufm.DefaultPKey); len(ib.GUIDs) != 2 {
+		t.Errorf("GUIDs of the default pkey are %v, expected %v", ib.GUIDs, []string{guid1, guid2})
+	}
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+const (
+	systemsPath = "/ufmRest/resources/systems"
+)
+
+func (u *UFM) ListSystems() ([]*IBSystem, *UFMError) {
+	return u.ListSystemsWithContext(context.Background())
+}
+
+// ListSystemsWithContext lists the hosts, switches and gateways in UFM.
+func (u *UFM) ListSystemsWithContext(ctx context.Context) ([]*IBSystem, *UFMError) {
+	data, err := u.client.GetWithContext(ctx, u.buildURL(systemsPath))
+	if err != nil {
+		return nil, wrapError(err, "failed to list systems")
+	}
+
+	return unmarshalSystems(data)
+}
+
+func (u *UFM) GetSystem(name string) (*IBSystem, *UFMError) {
+	return u.GetSystemWithContext(context.Background(), name)
+}
+
+// GetSystemWithContext gets the system by its ID, i.e. the IBPort.SystemID of its ports.
+func (u *UFM) GetSystemWithContext(ctx context.Context, name string) (*IBSystem, *UFMError) {
+	if name == "" {
+		return nil, &UFMError{
+			Code:    BadRequestErr,
+			Message: "the name of system is required",
+		}
+	}
+
+	data, err := u.client.GetWithContext(ctx, u.buildURL(systemsPath+"/"+url.PathEscape(name)))
+	if err != nil {
+		return nil, wrapError(err, "failed to get system %s", name)
+	}
+
+	// UFM returns a list of the system, which is empty if not found.
+	systems, ufmErr := unmarshalSystems(data)
+	if ufmErr != nil {
+		return nil, ufmErr
+	}
+	for _, s := range systems {
+		if s.Name == name {
+			return s, nil
+		}
+	}
+
+	return nil, &UFMError{
+		Code:    NotFoundErr,
+		Message: fmt.Sprintf("system %s not found", name),
+	}
+}
+
+func unmarshalSystems(data []byte) ([]*IBSystem, *UFMError) {
+	systems := []*IBSystem{}
+	if err := json.Unmarshal(data, &systems); err != nil {
+		return nil, &UFMError{
+			Code:    UnknownErr,
+			Message: fmt.Sprintf("failed to unmarshal systems with error: %v", err),
+			Err:     err,
+		}
+	}
+
+	return systems, nil
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufm
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// TLSConfig is the TLS configuration of the ufmclient to connect to UFM by https.
+type TLSConfig struct {
+	// Skip the verification of UFM's certificate; the system CAs are used if it's not skipped
+	// and no CA is provided.
+	InsecureSkipVerify bool
+	// The path of the CA bundle to verify UFM's certificate.
+	CAFile string
+	// The PEM encoded CA bundle to verify UFM's certificate.
+	CAData string
+	// The path of the client certificate and key for mTLS.
+	CertFile string
+	KeyFile  string
+	// Override the server name to verify UFM's certificate, e.g. when connecting by IP address.
+	ServerName string
+	// The minimum TLS version, e.g. tls.VersionTLS12; default to TLS 1.2.
+	MinVersion uint16
+}
+
+func (t *TLSConfig) build() (*tls.Config, error) {
+	conf := &tls.Config{
+		ServerName: t.ServerName,
+		MinVersion: t.MinVersion,
+	}
+	if conf.MinVersion == 0 {
+		conf.MinVersion = tls.VersionTLS12
+	}
+
+	if t.CAFile != "" || t.CAData != "" {
+		caCertPool := x509.NewCertPool()
+		if t.CAFile != "" {
+			data, err := os.ReadFile(t.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA file %s: %v", t.CAFile, err)
+			}
+			if !caCertPool.AppendCertsFromPEM(data) {
+				return nil, fmt.Errorf("no valid PEM certificate in CA file %s", t.CAFile)
+			}
+		}
+		if t.CAData != "" && !caCertPool.AppendCertsFromPEM([]byte(t.CAData)) {
+			return nil, fmt.Errorf("no valid PEM certificate in CA data")
+		}
+		conf.RootCAs = caCertPool
+	}
+
+	if t.InsecureSkipVerify {
+		/* #nosec */
+		conf.InsecureSkipVerify = true
+	}
+
+	if t.CertFile != "" || t.KeyFile != "" {
+		if t.CertFile == "" || t.KeyFile == "" {
+			return nil, fmt.Errorf("both client certificate and key are required for mTLS")
+		}
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+
+	return conf, nil
+}
+
+// ParseTLSVersion parses the TLS version, e.g. "1.2" or "1.3"; an empty string is parsed to 0.
+func ParseTLSVersion(ver string) (uint16, error) {
+	switch strings.TrimPrefix(strings.ToLower(ver), "tls") {
+	case "":
+		return 0, nil
+	case "1.0", "10":
+		return tls.VersionTLS10, nil
+	case "1.1", "11":
+		return tls.VersionTLS11, nil
+	case "1.2", "12":
+		return tls.VersionTLS12, nil
+	case "1.3", "13":
+		return tls.VersionTLS13, nil
+	}
+
+	return 0, fmt.Errorf("unknown TLS version %s", ver)
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufm_test
+
+import (
+	"encoding/pem"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/openbce/kperf/pkg/ufm"
+	"github.com/openbce/kperf/pkg/ufm/ufmtest"
+)
+
+func TestTLSVerify(t *testing.T) {
+	srv := ufmtest.NewTLSServer()
+	t.Cleanup(srv.Close)
+
+	caData := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}))
+
+	tests := []struct {
+		name     string
+		insecure bool
+		caData   string
+		wantErr  bool
+	}{
+		{name: "no CA", wantErr: true},
+		{name: "CA of the server", caData: caData},
+		{name: "insecure", insecure: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := srv.Config()
+			conf.Insecure = tt.insecure
+			conf.Certificate = tt.caData
+			conf.RetryMaxAttempts = 1
+			u, err := ufm.NewUFMWithConfig(conf, ufm.WithLogger(zerolog.Nop()))
+			if err != nil {
+				t.Fatalf("failed to create UFM: %v", err)
+			}
+
+			_, ufmErr := u.Version()
+			if tt.wantErr && ufmErr == nil {
+				t.Errorf("the certificate of UFM is not verified")
+			}
+			if !tt.wantErr && ufmErr != nil {
+				t.Errorf("failed to get version: %v", ufmErr)
+			}
+		})
+	}
+}
+
+func TestTLSInvalidCA(t *testing.T) {
+	srv := ufmtest.NewTLSServer()
+	t.Cleanup(srv.Close)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, []byte("not a pem"), 0600); err != nil {
+		t.Fatalf("failed to write CA file: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		set  func(conf *ufm.UFMConfig)
+	}{
+		{name: "CA data", set: func(conf *ufm.UFMConfig) { conf.Certificate = "not a pem" }},
+		{name: "CA file", set: func(conf *ufm.UFMConfig) { conf.CertificateFile = caFile }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := srv.Config()
+			conf.Insecure = false
+			tt.set(&conf)
+
+			_, err := ufm.NewUFMWithConfig(conf, ufm.WithLogger(zerolog.Nop()))
+			if err == nil {
+				t.Fatalf("the invalid CA is accepted")
+			}
+		})
+	}
+}
+
+func TestTLSDefaultTransport(t *testing.T) {
+	transport := http.DefaultTransport.(*http.Transport)
+	before := transport.TLSClientConfig
+
+	srv := ufmtest.NewTLSServer()
+	t.Cleanup(srv.Close)
+
+	conf := srv.Config()
+	conf.ServerName = "ufm"
+	if _, err := ufm.NewUFMWithConfig(conf, ufm.WithLogger(zerolog.Nop())); err != nil {
+		t.Fatalf("failed to create UFM: %v", err)
+	}
+
+	if transport.TLSClientConfig != before {
+		t.Errorf("the TLS config of http.DefaultTransport is modified")
+	}
+	if before != nil && (before.InsecureSkipVerify || before.ServerName == "ufm") {
+		t.Errorf("the TLS config of http.DefaultTransport is modified: %+v", before)
+	}
+}
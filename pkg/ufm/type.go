@@ -26,7 +26,7 @@ type IBNetwork struct {
 	// The pkeys for IB network. If not provided, it'll be generated automatically; the generated pkeys is only used by the service.
 	PKey int32 `json:"pkey"`
 	// Default false; create sharp allocation accordingly.
-	EnableSharp bool `json:"-"`
+	EnableSharp bool `json:"enable_sharp"`
 	// The GUID list of the IB network.
 	GUIDs []string `json:"guids"`
 	// Default 2k; one of 2k or 4k; the MTU of the services.
@@ -35,12 +35,31 @@ type IBNetwork struct {
 	IPOverIB bool `json:"ip_over_ib"`
 	// Default false; store the PKey at index 0 of the PKey table of the GUID.
 	Index0 bool `json:"index0"`
+	// The membership and index0 of each GUID; the GUIDs not in Members are full members
+	// with the Index0 of the IB network.
+	Members []GUIDMember `json:"members,omitempty"`
 	// Default is None, value can be range from 0-15
 	ServiceLevel int32 `json:"service_level"`
 	// Default is None, can be one of the following: 2.5, 10, 30, 5, 20, 40, 60, 80, 120, 14, 56, 112, 168, 25, 100, 200, or 300
 	RateLimit float64 `json:"rate_limit"`
 }
 
+type Membership string
+
+const (
+	FullMembership    Membership = "full"
+	LimitedMembership Membership = "limited"
+)
+
+// GUIDMember is a GUID in the IB network with its membership.
+type GUIDMember struct {
+	GUID string `json:"guid"`
+	// Default full; one of full or limited.
+	Membership Membership `json:"membership,omitempty"`
+	// Store the PKey at index 0 of the PKey table of the GUID.
+	Index0 bool `json:"index0"`
+}
+
 type IBPort struct {
 	Name            string `json:"name"`
 	GUID            string `json:"guid"`
@@ -59,6 +78,27 @@ type IBPort struct {
 	Module          string `json:"module"`
 }
 
+// IBSystem is a host, switch or gateway in UFM; the ports refer to it by IBPort.SystemID.
+type IBSystem struct {
+	// The ID of the system, i.e. the IBPort.SystemID of its ports.
+	Name string `json:"name"`
+	// The name of the system, e.g. the hostname.
+	SystemName  string `json:"system_name"`
+	GUID        string `json:"guid"`
+	Type        string `json:"type"`
+	Model       string `json:"model"`
+	Vendor      string `json:"vendor"`
+	Description string `json:"description"`
+	// The firmware version of the system.
+	FirmwareVersion string `json:"fw_version"`
+	// The role of the system in the fabric, e.g. tor, core or endpoint.
+	Role  string `json:"role"`
+	IP    string `json:"ip"`
+	State string `json:"state"`
+	// The names of the ports of the system.
+	Ports []string `json:"ports"`
+}
+
 type PKey struct {
 	Partition string `json:"partition"`
 	IPoIB     bool   `json:"ip_over_ib"`
@@ -74,6 +114,15 @@ type PKey struct {
 	}
 }
 
+// PatchResult is the GUIDs changed by the patch of an IB network; it's empty for the QoS.
+type PatchResult struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	// The GUIDs whose membership or index0 is updated.
+	Updated   []string `json:"updated,omitempty"`
+	Unchanged []string `json:"unchanged"`
+}
+
 type Field string
 
 const (
@@ -17,69 +17,78 @@ limitations under the License.
 package ufm
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"strings"
-
-	envv6 "github.com/caarlos0/env/v6"
 )
 
 type UFM struct {
-	conf   UFMConfig
-	client UFMClient
+	conf      UFMConfig
+	client    UFMClient
+	allocator *PKeyAllocator
 }
 
-const (
-	httpsProto = "https"
-	httpProto  = "http"
-)
+// NewUFM creates the UFM according to the environment values, e.g. UFM_ADDRESS.
+func NewUFM() (*UFM, error) {
+	ufmConf, err := UFMConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
 
-type UFMConfig struct {
-	Username    string `env:"UFM_USERNAME"`    // Username of ufm
-	Password    string `env:"UFM_PASSWORD"`    // Password of ufm
-	Address     string `env:"UFM_ADDRESS"`     // IP address or hostname of ufm server
-	Port        int    `env:"UFM_PORT"`        // REST API port of ufm
-	HTTPSchema  string `env:"UFM_HTTP_SCHEMA"` // http or https
-	Certificate string `env:"UFM_CERTIFICATE"` // Certificate of ufm
+	return NewUFMWithConfig(ufmConf)
 }
 
-func NewUFM() (*UFM, error) {
-	ufmConf := UFMConfig{}
-	if err := envv6.Parse(&ufmConf); err != nil {
-		return nil, err
+// NewUFMWithConfig creates the UFM according to the config and options; the
+// config is validated before connecting to UFM.
+func NewUFMWithConfig(ufmConf UFMConfig, opts ...Option) (*UFM, error) {
+	o := newOptions(opts...)
+
+	// The credentials are not required if the client is provided.
+	if errs := ufmConf.validate(o.client == nil); len(errs) != 0 {
+		return nil, &ConfigError{Errors: errs}
 	}
+	ufmConf.setDefaults()
 
-	if ufmConf.Username == "" || ufmConf.Password == "" || ufmConf.Address == "" {
-		return nil, fmt.Errorf("missing one or more required fileds for ufm [\"username\", \"password\", \"address\"]")
+	allocator := o.allocator
+	if allocator == nil {
+		allocator = ufmConf.pkeyAllocator()
 	}
 
-	// set httpSchema and port to ufm default if missing
-	ufmConf.HTTPSchema = strings.ToLower(ufmConf.HTTPSchema)
-	if ufmConf.HTTPSchema == "" {
-		ufmConf.HTTPSchema = httpsProto
+	if o.client != nil {
+		return &UFM{conf: ufmConf, client: o.client, allocator: allocator}, nil
 	}
-	if ufmConf.Port == 0 {
-		if ufmConf.HTTPSchema == httpsProto {
-			ufmConf.Port = 443
-		} else {
-			ufmConf.Port = 80
-		}
+
+	auth, err := newAuthenticator(&ufmConf)
+	if err != nil {
+		return nil, err
+	}
+	tlsConf, err := ufmConf.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	if o.retryPolicy == nil {
+		opts = append(opts, WithRetryPolicy(ufmConf.retryPolicy()))
 	}
 
 	isSecure := strings.EqualFold(ufmConf.HTTPSchema, httpsProto)
-	auth := &BasicAuth{Username: ufmConf.Username, Password: ufmConf.Password}
-	client, err := NewClient(isSecure, auth, ufmConf.Certificate)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create http ufmclient err: %v", err)
+	client, ufmErr := NewClient(isSecure, auth, tlsConf, opts...)
+	if ufmErr != nil {
+		return nil, fmt.Errorf("failed to create http ufmclient err: %v", ufmErr)
 	}
-	return &UFM{conf: ufmConf, client: client}, nil
+	return &UFM{conf: ufmConf, client: client, allocator: allocator}, nil
 }
 
 func (u *UFM) Version() (string, *UFMError) {
+	return u.VersionWithContext(context.Background())
+}
+
+func (u *UFM) VersionWithContext(ctx context.Context) (string, *UFMError) {
 	ver := struct {
 		Version string `json:"ufm_release_version"`
 	}{}
-	data, ufmErr := u.client.Get(u.buildURL("/ufmRest/app/ufm_version"))
+	data, ufmErr := u.client.GetWithContext(ctx, u.buildURL("/ufmRest/app/ufm_version"))
 	if ufmErr != nil {
 		return "", ufmErr
 	}
@@ -88,6 +97,7 @@ func (u *UFM) Version() (string, *UFMError) {
 		return "", &UFMError{
 			Code:    UnknownErr,
 			Message: err.Error(),
+			Err:     err,
 		}
 	}
 
@@ -95,6 +105,29 @@ func (u *UFM) Version() (string, *UFMError) {
 }
 
 func (u *UFM) GetIBNetwork(pkey int32) (*IBNetwork, *UFMError) {
+	return u.GetIBNetworkWithContext(context.Background(), pkey)
+}
+
+// GetIBNetworkWithContext returns the IB network of the pkey, including whether it has a SHARP
+// reservation.
+func (u *UFM) GetIBNetworkWithContext(ctx context.Context, pkey int32) (*IBNetwork, *UFMError) {
+	ib, ufmErr := u.getIBNetwork(ctx, pkey)
+	if ufmErr != nil {
+		return nil, ufmErr
+	}
+
+	enableSharp, ufmErr := u.hasSharpReservation(ctx, pkey)
+	if ufmErr != nil {
+		return nil, ufmErr
+	}
+	ib.EnableSharp = enableSharp
+
+	return ib, nil
+}
+
+// getIBNetwork returns the IB network of the pkey without checking its SHARP reservation, e.g.
+// for the internal checks of the GUIDs and QoS, which saves a request to UFM.
+func (u *UFM) getIBNetwork(ctx context.Context, pkey int32) (*IBNetwork, *UFMError) {
 	if !IsPKeyValid(pkey) {
 		return nil, &UFMError{
 			Code:    InvalidPKeyErr,
@@ -104,16 +137,13 @@ func (u *UFM) GetIBNetwork(pkey int32) (*IBNetwork, *UFMError) {
 
 	res := &PKey{}
 	path := fmt.Sprintf("/ufmRest/resources/pkeys/0x%x?guids_data=true&qos_conf=true", pkey)
-	if data, err := u.client.Get(u.buildURL(path)); err != nil {
-		return nil, &UFMError{
-			Code:    UnknownErr,
-			Message: fmt.Sprintf("failed to get pkey 0x%04X with error: %v", pkey, err),
-		}
+	if data, err := u.client.GetWithContext(ctx, u.buildURL(path)); err != nil {
+		return nil, wrapError(err, "failed to get pkey 0x%04X", pkey)
 	} else {
 		if string(data) == "{}" {
 			return nil, &UFMError{
 				Code:    NotFoundErr,
-				Message: "NotFound",
+				Message: fmt.Sprintf("pkey 0x%04X not found", pkey),
 			}
 		}
 
@@ -121,6 +151,7 @@ func (u *UFM) GetIBNetwork(pkey int32) (*IBNetwork, *UFMError) {
 			return nil, &UFMError{
 				Code:    UnknownErr,
 				Message: fmt.Sprintf("failed to unmarshal pkey 0x%04X with error: %v", pkey, err),
+				Err:     err,
 			}
 		}
 	}
@@ -128,19 +159,101 @@ func (u *UFM) GetIBNetwork(pkey int32) (*IBNetwork, *UFMError) {
 	return buildIBNetwork(pkey, res), nil
 }
 
-func (u *UFM) CreateIBNetwork(ib *IBNetwork) *UFMError {
-	if ufmErr := u.addGuids(ib); ufmErr != nil {
-		return ufmErr
+func (u *UFM) CreateIBNetwork(ib *IBNetwork, opts ...JobOption) *UFMError {
+	return u.CreateIBNetworkWithContext(context.Background(), ib, opts...)
+}
+
+// CreateIBNetworkWithContext creates the IB network; a free pkey is allocated and set to
+// ib.PKey if it's 0. If a step fails, the succeeded ones are rolled back: the pkey is deleted
+// if it's created by this call, otherwise only the added GUIDs are removed and the QoS is
+// restored; the UFMError wraps a SagaError of the steps. The jobs of UFM are waited unless
+// WithWait(false), in which case the later steps may run before the jobs finish.
+func (u *UFM) CreateIBNetworkWithContext(ctx context.Context, ib *IBNetwork, opts ...JobOption) *UFMError {
+	return u.createIBNetwork(ctx, ib, newJobOptions(opts...))
+}
+
+func (u *UFM) createIBNetwork(ctx context.Context, ib *IBNetwork, jo *jobOptions) *UFMError {
+	// The jobs are always waited on rollback.
+	undoJobs := jo.waited()
+
+	var cur *IBNetwork
+	var steps []sagaStep
+	if ib.PKey == 0 {
+		steps = append(steps, sagaStep{
+			name: "allocate-pkey",
+			do:   func(ctx context.Context) *UFMError { return u.claimPKey(ctx, ib, jo) },
+			undo: func(ctx context.Context) *UFMError {
+				// The failed claim has released the pkey by itself.
+				if ib.PKey == 0 {
+					return nil
+				}
+				return u.deleteIBNetwork(ctx, ib.PKey, undoJobs)
+			},
+		})
+	} else {
+		var ufmErr *UFMError
+		if cur, ufmErr = u.getIBNetwork(ctx, ib.PKey); ufmErr != nil {
+			if !ufmErr.IsNotFound() {
+				return ufmErr
+			}
+			cur = nil
+		}
+		steps = append(steps, u.addGUIDsStep(ib, cur, jo))
 	}
 
-	if ufmErr := u.Patch(ib, QoSField, AddStrategy); ufmErr != nil {
-		return ufmErr
+	qosStep := sagaStep{
+		name: "update-qos",
+		do:   func(ctx context.Context) *UFMError { return u.patchQoS(ctx, ib, SetStrategy, jo) },
+	}
+	// The QoS of the created pkey is deleted with the pkey; restore the existing one.
+	if cur != nil {
+		qosStep.undo = func(ctx context.Context) *UFMError { return u.patchQoS(ctx, cur, SetStrategy, undoJobs) }
+	}
+	steps = append(steps, qosStep)
+
+	if ib.EnableSharp {
+		steps = append(steps, sagaStep{
+			name: "create-sharp",
+			do:   func(ctx context.Context) *UFMError { return u.createSharpReservation(ctx, ib) },
+			undo: func(ctx context.Context) *UFMError { return u.deleteSharpReservation(ctx, ib.PKey) },
+		})
+	}
+
+	if sagaErr := runSaga(ctx, steps); sagaErr != nil {
+		return &UFMError{
+			Code:       sagaErr.Err.Code,
+			Message:    fmt.Sprintf("failed to create IB network 0x%04X: %v", ib.PKey, sagaErr),
+			StatusCode: sagaErr.Err.StatusCode,
+			Payload:    sagaErr.Err.Payload,
+			Err:        sagaErr,
+		}
 	}
 
 	return nil
 }
 
-func (u *UFM) patchQoS(ib *IBNetwork, _ Strategy) *UFMError {
+// addGUIDsStep adds the GUIDs of the IB network to the pkey; cur is nil if the pkey does
+// not exist, which is deleted on rollback.
+func (u *UFM) addGUIDsStep(ib, cur *IBNetwork, jo *jobOptions) sagaStep {
+	step := sagaStep{
+		name: "add-guids",
+		do:   func(ctx context.Context) *UFMError { return u.addGuids(ctx, ib, jo) },
+	}
+	if cur == nil {
+		step.undo = func(ctx context.Context) *UFMError { return u.deleteIBNetwork(ctx, ib.PKey, jo.waited()) }
+		return step
+	}
+
+	// Only remove the GUIDs added by the step from the existing pkey.
+	added, _, _ := diffGUIDs(memberGUIDs(cur.GUIDMembers()), memberGUIDs(ib.GUIDMembers()))
+	if len(added) != 0 {
+		step.undo = func(ctx context.Context) *UFMError { return u.deleteGuids(ctx, ib.withGUIDs(added), jo.waited()) }
+	}
+
+	return step
+}
+
+func (u *UFM) patchQoS(ctx context.Context, ib *IBNetwork, _ Strategy, jo *jobOptions) *UFMError {
 	pkey, _ := BuidPKey(ib.PKey)
 
 	qos := struct {
@@ -160,31 +273,31 @@ func (u *UFM) patchQoS(ib *IBNetwork, _ Strategy) *UFMError {
 		return &UFMError{
 			Code:    UnknownErr,
 			Message: fmt.Sprintf("failed to marshal IB with error: %v", err),
+			Err:     err,
 		}
 	}
 
-	if _, err := u.client.Put(u.buildURL("/ufmRest/resources/pkeys/qos_conf"), qosData); err != nil {
-		return &UFMError{
-			Code:    UnknownErr,
-			Message: fmt.Sprintf("failed to update PKey 0x%04X with error: %v", ib.PKey, err),
-		}
+	resp, ufmErr := u.client.DoWithContext(ctx, http.MethodPut, u.buildURL("/ufmRest/resources/pkeys/qos_conf"), qosData)
+	if ufmErr == nil {
+		_, ufmErr = u.trackJob(ctx, resp, jo)
+	}
+	if ufmErr != nil {
+		return wrapError(ufmErr, "failed to update PKey 0x%04X", ib.PKey)
 	}
 
 	return nil
 }
 
-func (u *UFM) listQoS() (map[string]PKey, *UFMError) {
-	if data, err := u.client.Get(u.buildURL("/ufmRest/resources/pkeys?qos_conf=true")); err != nil {
-		return nil, &UFMError{
-			Code:    UnknownErr,
-			Message: fmt.Sprintf("failed to list pkey with error: %v", err),
-		}
+func (u *UFM) listQoS(ctx context.Context) (map[string]PKey, *UFMError) {
+	if data, err := u.client.GetWithContext(ctx, u.buildURL("/ufmRest/resources/pkeys?qos_conf=true")); err != nil {
+		return nil, wrapError(err, "failed to list pkey")
 	} else {
 		pkeys := map[string]PKey{}
 		if err := json.Unmarshal(data, &pkeys); err != nil {
 			return nil, &UFMError{
 				Code:    UnknownErr,
 				Message: fmt.Sprintf("failed to unmarshal pkey with error: %v", err),
+				Err:     err,
 			}
 		}
 
@@ -192,18 +305,16 @@ func (u *UFM) listQoS() (map[string]PKey, *UFMError) {
 	}
 }
 
-func (u *UFM) listGUID() (map[string]PKey, *UFMError) {
-	if data, err := u.client.Get(u.buildURL("/ufmRest/resources/pkeys?guids_data=true")); err != nil {
-		return nil, &UFMError{
-			Code:    UnknownErr,
-			Message: fmt.Sprintf("failed to list pkey with error: %v", err),
-		}
+func (u *UFM) listGUID(ctx context.Context) (map[string]PKey, *UFMError) {
+	if data, err := u.client.GetWithContext(ctx, u.buildURL("/ufmRest/resources/pkeys?guids_data=true")); err != nil {
+		return nil, wrapError(err, "failed to list pkey")
 	} else {
 		pkeys := map[string]PKey{}
 		if err := json.Unmarshal(data, &pkeys); err != nil {
 			return nil, &UFMError{
 				Code:    UnknownErr,
 				Message: fmt.Sprintf("failed to unmarshal pkey with error: %v", err),
+				Err:     err,
 			}
 		}
 
@@ -212,12 +323,20 @@ func (u *UFM) listGUID() (map[string]PKey, *UFMError) {
 }
 
 func (u *UFM) ListIBNetwork() ([]*IBNetwork, *UFMError) {
-	var res []*IBNetwork
-	qos, ufmErr := u.listQoS()
+	return u.ListIBNetworkWithContext(context.Background())
+}
+
+func (u *UFM) ListIBNetworkWithContext(ctx context.Context) ([]*IBNetwork, *UFMError) {
+	res := []*IBNetwork{}
+	qos, ufmErr := u.listQoS(ctx)
+	if ufmErr != nil {
+		return nil, ufmErr
+	}
+	guids, ufmErr := u.listGUID(ctx)
 	if ufmErr != nil {
 		return nil, ufmErr
 	}
-	guids, ufmErr := u.listGUID()
+	sharp, ufmErr := u.listSharpReservations(ctx)
 	if ufmErr != nil {
 		return nil, ufmErr
 	}
@@ -231,33 +350,67 @@ func (u *UFM) ListIBNetwork() ([]*IBNetwork, *UFMError) {
 		if err != nil {
 			continue
 		}
-		res = append(res, buildIBNetwork(pkey, &param))
+		ib := buildIBNetwork(pkey, &param)
+		ib.EnableSharp = sharp[pkey]
+		res = append(res, ib)
 	}
 
 	return res, nil
 }
 
-func (u *UFM) DeleteIBNetwork(pkey int32) *UFMError {
-	path := fmt.Sprintf("/ufmRest/resources/pkeys/0x%x", pkey)
-	if _, err := u.client.Delete(u.buildURL(path)); err != nil {
-		return &UFMError{
-			Code:    UnknownErr,
-			Message: fmt.Sprintf("failed to delete PKey 0x%04X with error: %v", pkey, err),
+func (u *UFM) DeleteIBNetwork(pkey int32, opts ...JobOption) *UFMError {
+	return u.DeleteIBNetworkWithContext(context.Background(), pkey, opts...)
+}
+
+// DeleteIBNetworkWithContext deletes the pkey and its SHARP reservation if any; the job of UFM
+// is waited unless WithWait(false).
+func (u *UFM) DeleteIBNetworkWithContext(ctx context.Context, pkey int32, opts ...JobOption) *UFMError {
+	return u.deleteIBNetwork(ctx, pkey, newJobOptions(opts...))
+}
+
+func (u *UFM) deleteIBNetwork(ctx context.Context, pkey int32, jo *jobOptions) *UFMError {
+	enableSharp, ufmErr := u.hasSharpReservation(ctx, pkey)
+	if ufmErr != nil {
+		return ufmErr
+	}
+	if enableSharp {
+		if ufmErr := u.deleteSharpReservation(ctx, pkey); ufmErr != nil {
+			return ufmErr
 		}
 	}
 
+	path := fmt.Sprintf("/ufmRest/resources/pkeys/0x%x", pkey)
+	resp, ufmErr := u.client.DoWithContext(ctx, http.MethodDelete, u.buildURL(path), nil)
+	if ufmErr == nil {
+		_, ufmErr = u.trackJob(ctx, resp, jo)
+	}
+	if ufmErr != nil {
+		return wrapError(ufmErr, "failed to delete PKey 0x%04X", pkey)
+	}
+
 	return nil
 }
 
-func (u *UFM) Patch(ib *IBNetwork, field Field, op Strategy) *UFMError {
+func (u *UFM) Patch(ib *IBNetwork, field Field, op Strategy, opts ...JobOption) (*PatchResult, *UFMError) {
+	return u.PatchWithContext(context.Background(), ib, field, op, opts...)
+}
+
+// PatchWithContext patches the field of the IB network by the strategy; the set strategy of
+// GUIDs replaces the GUIDs of the IB network, including removing the ones not in ib.GUIDs.
+// The jobs of UFM, e.g. a large update of GUIDs, are waited unless WithWait(false).
+func (u *UFM) PatchWithContext(ctx context.Context, ib *IBNetwork, field Field, op Strategy, opts ...JobOption) (*PatchResult, *UFMError) {
+	jo := newJobOptions(opts...)
 	switch field {
 	case GUIDField:
-		return u.patchGUIDs(ib, op)
+		return u.patchGUIDs(ctx, ib, op, jo)
 	case QoSField:
-		return u.patchQoS(ib, op)
+		if ufmErr := u.patchQoS(ctx, ib, op, jo); ufmErr != nil {
+			return nil, ufmErr
+		}
+		return &PatchResult{}, nil
 	}
 
-	return &UFMError{
+	return nil, &UFMError{
 		Code:    UnknownErr,
 		Message: "Invalid field",
 	}
@@ -267,20 +420,72 @@ func (u *UFM) buildURL(path string) string {
 	return fmt.Sprintf("%s://%s:%d%s", u.conf.HTTPSchema, u.conf.Address, u.conf.Port, path)
 }
 
-func (u *UFM) patchGUIDs(ib *IBNetwork, op Strategy) *UFMError {
+func (u *UFM) patchGUIDs(ctx context.Context, ib *IBNetwork, op Strategy, jo *jobOptions) (*PatchResult, *UFMError) {
 	switch op {
 	case AddStrategy:
-		return u.addGuids(ib)
+		if ufmErr := u.addGuids(ctx, ib, jo); ufmErr != nil {
+			return nil, ufmErr
+		}
+		return &PatchResult{Added: memberGUIDs(ib.GUIDMembers())}, nil
 	case DeleteStrategy:
-		return u.deleteGuids(ib)
+		if ufmErr := u.deleteGuids(ctx, ib, jo); ufmErr != nil {
+			return nil, ufmErr
+		}
+		return &PatchResult{Removed: memberGUIDs(ib.GUIDMembers())}, nil
 	case SetStrategy:
-		return u.addGuids(ib)
+		return u.setGuids(ctx, ib, jo)
 	}
 
-	return nil
+	return nil, &UFMError{
+		Code:    UnknownErr,
+		Message: fmt.Sprintf("Invalid strategy %s", op),
+	}
+}
+
+// setGuids replaces the GUIDs of the IB network by ib.GUIDs, and updates the membership and index0
+// of the existing ones; the IB network is created if not found. All the GUIDs are removed if
+// ib.GUIDs is empty, so the callers should confirm it.
+func (u *UFM) setGuids(ctx context.Context, ib *IBNetwork, jo *jobOptions) (*PatchResult, *UFMError) {
+	cur, ufmErr := u.getIBNetwork(ctx, ib.PKey)
+	if ufmErr != nil {
+		if !ufmErr.IsNotFound() {
+			return nil, ufmErr
+		}
+		cur = &IBNetwork{PKey: ib.PKey}
+	}
+
+	res := &PatchResult{}
+	res.Added, res.Removed, res.Unchanged = diffGUIDs(memberGUIDs(cur.GUIDMembers()), memberGUIDs(ib.GUIDMembers()))
+	res.Updated, _ = diffMembers(cur, ib)
+	if len(res.Updated) != 0 {
+		updated := map[string]bool{}
+		for _, g := range res.Updated {
+			updated[g] = true
+		}
+		var unchanged []string
+		for _, g := range res.Unchanged {
+			if !updated[g] {
+				unchanged = append(unchanged, g)
+			}
+		}
+		res.Unchanged = unchanged
+	}
+
+	if toAdd := append(append([]string{}, res.Added...), res.Updated...); len(toAdd) != 0 {
+		if ufmErr := u.addGuids(ctx, ib.withGUIDs(toAdd), jo); ufmErr != nil {
+			return nil, ufmErr
+		}
+	}
+	if len(res.Removed) != 0 {
+		if ufmErr := u.deleteGuids(ctx, ib.withGUIDs(res.Removed), jo); ufmErr != nil {
+			return nil, ufmErr
+		}
+	}
+
+	return res, nil
 }
 
-func (u *UFM) deleteGuids(ib *IBNetwork) *UFMError {
+func (u *UFM) deleteGuids(ctx context.Context, ib *IBNetwork, jo *jobOptions) *UFMError {
 	pkey, _ := BuidPKey(ib.PKey)
 
 	guidList := struct {
@@ -288,7 +493,7 @@ func (u *UFM) deleteGuids(ib *IBNetwork) *UFMError {
 		GUIDs []string `json:"guids"`
 	}{
 		PKey:  pkey,
-		GUIDs: ib.GUIDs,
+		GUIDs: memberGUIDs(ib.GUIDMembers()),
 	}
 
 	data, err := json.Marshal(guidList)
@@ -296,48 +501,79 @@ func (u *UFM) deleteGuids(ib *IBNetwork) *UFMError {
 		return &UFMError{
 			Code:    UnknownErr,
 			Message: fmt.Sprintf("failed to marshal IB with error: %v", err),
+			Err:     err,
 		}
 	}
 
-	if _, err := u.client.Post(u.buildURL("/ufmRest/actions/remove_guids_from_pkey"), data); err != nil {
-		return &UFMError{
-			Code:    UnknownErr,
-			Message: fmt.Sprintf("failed to create PKey 0x%04X with error: %v", ib.PKey, err),
-		}
+	// Removing GUIDs from a pkey is idempotent, it's safe to retry.
+	resp, ufmErr := u.client.DoWithContext(WithIdempotent(ctx), http.MethodPost, u.buildURL("/ufmRest/actions/remove_guids_from_pkey"), data)
+	if ufmErr == nil {
+		_, ufmErr = u.trackJob(ctx, resp, jo)
+	}
+	if ufmErr != nil {
+		return wrapError(ufmErr, "failed to remove GUIDs from PKey 0x%04X", ib.PKey)
 	}
 
 	return nil
 }
 
-func (u *UFM) addGuids(ib *IBNetwork) *UFMError {
+// addGuids adds the GUIDs to the pkey by one request per membership and index0, as UFM
+// accepts only one of them in a request.
+func (u *UFM) addGuids(ctx context.Context, ib *IBNetwork, jo *jobOptions) *UFMError {
 	pkey, _ := BuidPKey(ib.PKey)
 
-	guidList := struct {
-		PKey       string   `json:"pkey"`
-		IPoIB      bool     `json:"ip_over_ib"`
-		Index0     bool     `json:"index0"`
-		GUIDs      []string `json:"guids"`
-		Membership string   `json:"membership"`
-	}{
-		PKey:       pkey,
-		IPoIB:      ib.IPOverIB,
-		Membership: "full",
-		Index0:     ib.Index0,
-		GUIDs:      ib.GUIDs,
+	type guidGroup struct {
+		membership Membership
+		index0     bool
 	}
-
-	data, err := json.Marshal(guidList)
-	if err != nil {
-		return &UFMError{
-			Code:    UnknownErr,
-			Message: fmt.Sprintf("failed to marshal IB with error: %v", err),
+	var groups []guidGroup
+	groupGUIDs := map[guidGroup][]string{}
+	for _, m := range ib.GUIDMembers() {
+		g := guidGroup{membership: m.Membership, index0: m.Index0}
+		if _, found := groupGUIDs[g]; !found {
+			groups = append(groups, g)
 		}
+		groupGUIDs[g] = append(groupGUIDs[g], m.GUID)
+	}
+	if len(groups) == 0 {
+		g := guidGroup{membership: FullMembership, index0: ib.Index0}
+		groups = append(groups, g)
+		groupGUIDs[g] = []string{}
 	}
 
-	if _, err := u.client.Post(u.buildURL("/ufmRest/resources/pkeys"), data); err != nil {
-		return &UFMError{
-			Code:    UnknownErr,
-			Message: fmt.Sprintf("failed to create PKey 0x%04X with error: %v", ib.PKey, err),
+	for _, g := range groups {
+		guidList := struct {
+			PKey       string   `json:"pkey"`
+			Name       string   `json:"partition,omitempty"`
+			IPoIB      bool     `json:"ip_over_ib"`
+			Index0     bool     `json:"index0"`
+			GUIDs      []string `json:"guids"`
+			Membership string   `json:"membership"`
+		}{
+			PKey:       pkey,
+			Name:       ib.Name,
+			IPoIB:      ib.IPOverIB,
+			Membership: string(g.membership),
+			Index0:     g.index0,
+			GUIDs:      groupGUIDs[g],
+		}
+
+		data, err := json.Marshal(guidList)
+		if err != nil {
+			return &UFMError{
+				Code:    UnknownErr,
+				Message: fmt.Sprintf("failed to marshal IB with error: %v", err),
+				Err:     err,
+			}
+		}
+
+		// Adding GUIDs to a pkey is idempotent, it's safe to retry.
+		resp, ufmErr := u.client.DoWithContext(WithIdempotent(ctx), http.MethodPost, u.buildURL("/ufmRest/resources/pkeys"), data)
+		if ufmErr == nil {
+			_, ufmErr = u.trackJob(ctx, resp, jo)
+		}
+		if ufmErr != nil {
+			return wrapError(ufmErr, "failed to create PKey 0x%04X", ib.PKey)
 		}
 	}
 
@@ -345,41 +581,52 @@ func (u *UFM) addGuids(ib *IBNetwork) *UFMError {
 }
 
 func (u *UFM) ListPort(guids ...string) ([]*IBPort, *UFMError) {
-	if data, err := u.client.Get(u.buildURL(fmt.Sprintf("/ufmRest/resources/ports?sys_type=Computer"))); err != nil {
-		return nil, &UFMError{
-			Code:    UnknownErr,
-			Message: fmt.Sprintf("failed to list pkey with error: %v", err),
-		}
-	} else {
-		ports := []*IBPort{}
-		if err := json.Unmarshal(data, &ports); err != nil {
-			return nil, &UFMError{
-				Code:    UnknownErr,
-				Message: fmt.Sprintf("failed to unmarshal ports with error: %v", err),
-			}
-		}
+	return u.ListPortWithContext(context.Background(), guids...)
+}
 
-		var res []*IBPort
-		filtered := false
+// ListPortWithContext lists the ports of the computers; only the ports of the GUIDs are
+// returned if any.
+func (u *UFM) ListPortWithContext(ctx context.Context, guids ...string) ([]*IBPort, *UFMError) {
+	return u.QueryPortsWithContext(ctx, &PortQuery{SystemType: ComputerSystem, GUIDs: guids})
+}
 
-		for _, p := range ports {
-			if len(guids) != 0 {
-				filtered = true
+func (u *UFM) QueryPorts(query *PortQuery) ([]*IBPort, *UFMError) {
+	return u.QueryPortsWithContext(context.Background(), query)
+}
 
-				guidMap := make(map[string]struct{})
-				for _, g := range guids {
-					guidMap[g] = struct{}{}
-				}
-				if _, found := guidMap[p.GUID]; found {
-					res = append(res, p)
-				}
-			}
-		}
+// QueryPortsWithContext lists the ports matching the query; the options supported by UFM are
+// sent in the request, and the others are filtered locally.
+func (u *UFM) QueryPortsWithContext(ctx context.Context, query *PortQuery) ([]*IBPort, *UFMError) {
+	if query == nil {
+		query = &PortQuery{}
+	}
+
+	path := "/ufmRest/resources/ports"
+	if values := query.values(); len(values) != 0 {
+		path += "?" + values.Encode()
+	}
 
-		if !filtered {
-			res = ports
+	data, err := u.client.GetWithContext(ctx, u.buildURL(path))
+	if err != nil {
+		return nil, wrapError(err, "failed to list ports")
+	}
+
+	ports := []*IBPort{}
+	if err := json.Unmarshal(data, &ports); err != nil {
+		return nil, &UFMError{
+			Code:    UnknownErr,
+			Message: fmt.Sprintf("failed to unmarshal ports with error: %v", err),
+			Err:     err,
 		}
+	}
 
-		return res, nil
+	res := []*IBPort{}
+	matcher := query.matcher()
+	for _, p := range ports {
+		if matcher(p) {
+			res = append(res, p)
+		}
 	}
+
+	return res, nil
 }
@@ -0,0 +1,288 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufm_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/openbce/kperf/pkg/ufm"
+	"github.com/openbce/kperf/pkg/ufm/ufmtest"
+)
+
+const (
+	guid1 = "0x0002c90300a1b2c1"
+	guid2 = "0x0002c90300a1b2c2"
+	guid3 = "0x0002c90300a1b2c3"
+)
+
+// newUFM starts a fake UFM server and connects to it; the retries are not delayed.
+func newUFM(t *testing.T, authType string) (*ufm.UFM, *ufmtest.Server) {
+	t.Helper()
+
+	srv := ufmtest.NewServer()
+	t.Cleanup(srv.Close)
+
+	conf := srv.Config()
+	conf.AuthType = authType
+	conf.RetryBackoff = time.Millisecond
+	u, err := ufm.NewUFMWithConfig(conf, ufm.WithLogger(zerolog.Nop()))
+	if err != nil {
+		t.Fatalf("failed to create UFM: %v", err)
+	}
+
+	return u, srv
+}
+
+func countRequests(srv *ufmtest.Server, method, path string) int {
+	n := 0
+	for _, r := range srv.Requests() {
+		if r.Method == method && r.Path == path {
+			n++
+		}
+	}
+
+	return n
+}
+
+func TestVersion(t *testing.T) {
+	u, _ := newUFM(t, "")
+
+	ver, err := u.Version()
+	if err != nil {
+		t.Fatalf("failed to get version: %v", err)
+	}
+	if ver != ufmtest.DefaultVersion {
+		t.Errorf("version is %q, expected %q", ver, ufmtest.DefaultVersion)
+	}
+}
+
+func TestIBNetworkLifecycle(t *testing.T) {
+	u, srv := newUFM(t, "")
+
+	ib := &ufm.IBNetwork{
+		Name:         "tenant-a",
+		PKey:         0x100,
+		GUIDs:        []string{guid1, guid2},
+		MTU:          4096,
+		IPOverIB:     true,
+		ServiceLevel: 3,
+		RateLimit:    100,
+	}
+	if err := u.CreateIBNetwork(ib); err != nil {
+		t.Fatalf("failed to create IB network: %v", err)
+	}
+
+	got, err := u.GetIBNetwork(0x100)
+	if err != nil {
+		t.Fatalf("failed to get IB network: %v", err)
+	}
+	if got.Name != "tenant-a" || !got.IPOverIB || got.ServiceLevel != 3 || got.RateLimit != 100 {
+		t.Errorf("unexpected IB network %+v", got)
+	}
+	if !reflect.DeepEqual(got.GUIDs, []string{guid1, guid2}) {
+		t.Errorf("GUIDs are %v, expected %v", got.GUIDs, []string{guid1, guid2})
+	}
+
+	list, err := u.ListIBNetwork()
+	if err != nil {
+		t.Fatalf("failed to list IB networks: %v", err)
+	}
+	pkeys := map[int32]bool{}
+	for _, n := range list {
+		pkeys[n.PKey] = true
+	}
+	if !pkeys[0x100] || !pkeys[ufm.DefaultPKey] || len(pkeys) != 2 {
+		t.Errorf("listed pkeys are %v, expected 0x100 and the default pkey", pkeys)
+	}
+
+	if err := u.DeleteIBNetwork(0x100); err != nil {
+		t.Fatalf("failed to delete IB network: %v", err)
+	}
+	if _, found := srv.GetIBNetwork(0x100); found {
+		t.Errorf("pkey 0x100 is not deleted")
+	}
+	if _, err := u.GetIBNetwork(0x100); err == nil || !err.IsNotFound() {
+		t.Errorf("expected not found after delete, got %v", err)
+	}
+}
+
+func TestPatchGUIDs(t *testing.T) {
+	u, srv := newUFM(t, "")
+	srv.AddIBNetwork(&ufm.IBNetwork{PKey: 0x100, GUIDs: []string{guid1}})
+
+	res, err := u.Patch(&ufm.IBNetwork{PKey: 0x100, GUIDs: []string{guid2}}, ufm.GUIDField, ufm.AddStrategy)
+	if err != nil {
+		t.Fatalf("failed to add GUIDs: %v", err)
+	}
+	if !reflect.DeepEqual(res.Added, []string{guid2}) {
+		t.Errorf("added GUIDs are %v, expected %v", res.Added, []string{guid2})
+	}
+
+	res, err = u.Patch(&ufm.IBNetwork{PKey: 0x100, GUIDs: []string{guid2, guid3}}, ufm.GUIDField, ufm.SetStrategy)
+	if err != nil {
+		t.Fatalf("failed to set GUIDs: %v", err)
+	}
+	if !reflect.DeepEqual(res.Added, []string{guid3}) || !reflect.DeepEqual(res.Removed, []string{guid1}) {
+		t.Errorf("unexpected result of set %+v", res)
+	}
+
+	if _, err := u.Patch(&ufm.IBNetwork{PKey: 0x100, GUIDs: []string{guid3}}, ufm.GUIDField, ufm.DeleteStrategy); err != nil {
+		t.Fatalf("failed to delete GUIDs: %v", err)
+	}
+	ib, _ := srv.GetIBNetwork(0x100)
+	if !reflect.DeepEqual(ib.GUIDs, []string{guid2}) {
+		t.Errorf("GUIDs are %v, expected %v", ib.GUIDs, []string{guid2})
+	}
+
+	if _, err := u.Patch(&ufm.IBNetwork{PKey: 0x100, MTU: 4096, ServiceLevel: 5, RateLimit: 200}, ufm.QoSField, ufm.SetStrategy); err != nil {
+		t.Fatalf("failed to update QoS: %v", err)
+	}
+	ib, _ = srv.GetIBNetwork(0x100)
+	if ib.ServiceLevel != 5 || ib.RateLimit != 200 {
+		t.Errorf("QoS is not updated: %+v", ib)
+	}
+}
+
+func TestRetryOnServerError(t *testing.T) {
+	u, srv := newUFM(t, "")
+	srv.AddFault(ufmtest.Fault{Method: http.MethodGet, Path: "/ufmRest/app/ufm_version", StatusCode: http.StatusServiceUnavailable, Times: 2})
+
+	if _, err := u.Version(); err != nil {
+		t.Fatalf("failed to get version after retries: %v", err)
+	}
+	if n := countRequests(srv, http.MethodGet, "/ufmRest/app/ufm_version"); n != 3 {
+		t.Errorf("sent %d requests, expected 3", n)
+	}
+
+	srv.ClearFaults()
+	srv.ResetRequests()
+	srv.AddFault(ufmtest.Fault{Method: http.MethodGet, Path: "/ufmRest/app/ufm_version", StatusCode: http.StatusServiceUnavailable})
+
+	_, err := u.Version()
+	if err == nil {
+		t.Fatalf("expected error after the attempts are exhausted")
+	}
+	if err.Attempts != 3 || err.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("unexpected error %+v", err)
+	}
+}
+
+func TestNoRetryOnClientError(t *testing.T) {
+	u, srv := newUFM(t, "")
+	srv.AddFault(ufmtest.Fault{Method: http.MethodGet, Path: "/ufmRest/app/ufm_version", StatusCode: http.StatusBadRequest})
+
+	if _, err := u.Version(); err == nil {
+		t.Fatalf("expected error of bad request")
+	}
+	if n := countRequests(srv, http.MethodGet, "/ufmRest/app/ufm_version"); n != 1 {
+		t.Errorf("sent %d requests, expected 1", n)
+	}
+}
+
+func TestSessionRelogin(t *testing.T) {
+	u, srv := newUFM(t, string(ufm.SessionAuthType))
+
+	if _, err := u.Version(); err != nil {
+		t.Fatalf("failed to get version: %v", err)
+	}
+	if _, err := u.Version(); err != nil {
+		t.Fatalf("failed to get version: %v", err)
+	}
+	if n := countRequests(srv, http.MethodPost, "/dologin"); n != 1 {
+		t.Fatalf("logged in %d times, expected 1", n)
+	}
+
+	srv.ExpireSessions()
+	if _, err := u.Version(); err != nil {
+		t.Fatalf("failed to get version after the session expired: %v", err)
+	}
+	if n := countRequests(srv, http.MethodPost, "/dologin"); n != 2 {
+		t.Errorf("logged in %d times, expected 2", n)
+	}
+
+	// The expired session must not be valid again after another client logs in.
+	conf := srv.Config()
+	conf.AuthType = string(ufm.SessionAuthType)
+	other, err := ufm.NewUFMWithConfig(conf, ufm.WithLogger(zerolog.Nop()))
+	if err != nil {
+		t.Fatalf("failed to create UFM: %v", err)
+	}
+	srv.ExpireSessions()
+	if _, err := other.Version(); err != nil {
+		t.Fatalf("failed to get version: %v", err)
+	}
+	if _, err := u.Version(); err != nil {
+		t.Fatalf("failed to get version after the session expired: %v", err)
+	}
+	if n := countRequests(srv, http.MethodPost, "/dologin"); n != 4 {
+		t.Errorf("logged in %d times, expected 4", n)
+	}
+}
+
+func TestSessionLoginFailed(t *testing.T) {
+	srv := ufmtest.NewServer()
+	defer srv.Close()
+
+	conf := srv.Config()
+	conf.AuthType = string(ufm.SessionAuthType)
+	conf.Password = "wrong"
+	u, err := ufm.NewUFMWithConfig(conf, ufm.WithLogger(zerolog.Nop()))
+	if err != nil {
+		t.Fatalf("failed to create UFM: %v", err)
+	}
+
+	ufmErr := u.DeleteIBNetwork(0x100)
+	if ufmErr == nil || ufmErr.Code != ufm.AuthErr {
+		t.Errorf("expected auth error, got %v", ufmErr)
+	}
+}
+
+func TestPatchFailed(t *testing.T) {
+	u, srv := newUFM(t, "")
+	srv.AddIBNetwork(&ufm.IBNetwork{PKey: 0x100, GUIDs: []string{guid1}})
+	srv.AddFault(ufmtest.Fault{Method: http.MethodPost, Path: "/ufmRest/resources/pkeys", StatusCode: http.StatusBadRequest})
+	srv.AddFault(ufmtest.Fault{Method: http.MethodPost, Path: "/ufmRest/actions/remove_guids_from_pkey", StatusCode: http.StatusBadRequest})
+
+	for _, op := range []ufm.Strategy{ufm.AddStrategy, ufm.DeleteStrategy, ufm.SetStrategy} {
+		res, err := u.Patch(&ufm.IBNetwork{PKey: 0x100, GUIDs: []string{guid2}}, ufm.GUIDField, op)
+		if err == nil || res != nil {
+			t.Errorf("%s: expected error without result, got %+v, %v", op, res, err)
+		}
+	}
+}
+
+func TestListNoIBNetworks(t *testing.T) {
+	u, _ := newUFM(t, "")
+	if err := u.DeleteIBNetwork(ufm.DefaultPKey); err != nil {
+		t.Fatalf("failed to delete IB network: %v", err)
+	}
+
+	list, err := u.ListIBNetwork()
+	if err != nil {
+		t.Fatalf("failed to list IB networks: %v", err)
+	}
+	// No IB network is an empty list rather than null.
+	if data, _ := json.Marshal(list); string(data) != "[]" {
+		t.Errorf("no IB networks are %s, expected []", data)
+	}
+}
@@ -0,0 +1,228 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ufmconfig loads and saves the config file of the ufm command line, which
+// holds multiple named contexts of UFM, similar to kubeconfig, e.g.
+//
+//	current-context: staging
+//	contexts:
+//	- name: staging
+//	  context:
+//	    address: ufm-staging.example.com
+//	    schema: https
+//	    credential: staging-admin
+//	    certificate-authority: /etc/ufm/staging-ca.pem
+//	credentials:
+//	- name: staging-admin
+//	  credential:
+//	    username: admin
+//	    password: "123456"
+package ufmconfig
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/openbce/kperf/pkg/ufm"
+)
+
+// EnvConfigPath is the environment value to override the path of the config file.
+const EnvConfigPath = "UFM_CONFIG"
+
+type Config struct {
+	CurrentContext string            `json:"current-context,omitempty"`
+	Contexts       []NamedContext    `json:"contexts,omitempty"`
+	Credentials    []NamedCredential `json:"credentials,omitempty"`
+}
+
+type NamedContext struct {
+	Name    string  `json:"name"`
+	Context Context `json:"context"`
+}
+
+// Context is the connection to a UFM.
+type Context struct {
+	// IP address or hostname of ufm server.
+	Address string `json:"address"`
+	// REST API port of ufm; default to 443 for https and 80 for http.
+	Port int `json:"port,omitempty"`
+	// http or https; default to https.
+	Schema string `json:"schema,omitempty"`
+	// The name of the credential in the config to connect to ufm.
+	Credential string `json:"credential,omitempty"`
+	// The path of the CA bundle of ufm.
+	CertificateAuthority string `json:"certificate-authority,omitempty"`
+	// The PEM encoded CA bundle of ufm.
+	CertificateAuthorityData string `json:"certificate-authority-data,omitempty"`
+	// Skip the verification of ufm certificate.
+	InsecureSkipVerify bool `json:"insecure-skip-verify,omitempty"`
+}
+
+type NamedCredential struct {
+	Name       string     `json:"name"`
+	Credential Credential `json:"credential"`
+}
+
+// Credential is the credential to connect to a UFM.
+type Credential struct {
+	// basic, token or session; the same as UFM_AUTH_TYPE.
+	AuthType string `json:"auth-type,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Token    string `json:"token,omitempty"`
+}
+
+// DefaultPath returns the path of the config file, which is $UFM_CONFIG or ~/.ufm/config.
+func DefaultPath() (string, error) {
+	if p := os.Getenv(EnvConfigPath); p != "" {
+		return p, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".ufm", "config"), nil
+}
+
+// Load reads the config file; an empty config is returned if the file does not exist.
+func Load(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Config{}, nil
+		}
+		return nil, err
+	}
+
+	conf := &Config{}
+	if err := yaml.UnmarshalStrict(data, conf); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+
+	return conf, nil
+}
+
+// Save writes the config file, which is only accessible by the owner as it holds the credentials.
+func (c *Config) Save(path string) error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// Context returns the context by name.
+func (c *Config) Context(name string) (*Context, bool) {
+	for i := range c.Contexts {
+		if c.Contexts[i].Name == name {
+			return &c.Contexts[i].Context, true
+		}
+	}
+
+	return nil, false
+}
+
+// SetContext creates or replaces the context by name.
+func (c *Config) SetContext(name string, ctx Context) {
+	if cur, found := c.Context(name); found {
+		*cur = ctx
+		return
+	}
+
+	c.Contexts = append(c.Contexts, NamedContext{Name: name, Context: ctx})
+	sort.Slice(c.Contexts, func(i, j int) bool { return c.Contexts[i].Name < c.Contexts[j].Name })
+}
+
+// UseContext sets the current context.
+func (c *Config) UseContext(name string) error {
+	if _, found := c.Context(name); !found {
+		return fmt.Errorf("context %q not found", name)
+	}
+
+	c.CurrentContext = name
+	return nil
+}
+
+// Credential returns the credential by name.
+func (c *Config) Credential(name string) (*Credential, bool) {
+	for i := range c.Credentials {
+		if c.Credentials[i].Name == name {
+			return &c.Credentials[i].Credential, true
+		}
+	}
+
+	return nil, false
+}
+
+// SetCredential creates or replaces the credential by name.
+func (c *Config) SetCredential(name string, cred Credential) {
+	if cur, found := c.Credential(name); found {
+		*cur = cred
+		return
+	}
+
+	c.Credentials = append(c.Credentials, NamedCredential{Name: name, Credential: cred})
+	sort.Slice(c.Credentials, func(i, j int) bool { return c.Credentials[i].Name < c.Credentials[j].Name })
+}
+
+// UFMConfig builds the UFMConfig of the context; the current context is used if the name
+// is empty, and an empty UFMConfig is returned if there's no current context either.
+func (c *Config) UFMConfig(name string) (ufm.UFMConfig, error) {
+	conf := ufm.UFMConfig{}
+	if name == "" {
+		name = c.CurrentContext
+	}
+	if name == "" {
+		return conf, nil
+	}
+
+	ctx, found := c.Context(name)
+	if !found {
+		return conf, fmt.Errorf("context %q not found", name)
+	}
+
+	conf.Address = ctx.Address
+	conf.Port = ctx.Port
+	conf.HTTPSchema = ctx.Schema
+	conf.CertificateFile = ctx.CertificateAuthority
+	conf.Certificate = ctx.CertificateAuthorityData
+	conf.Insecure = ctx.InsecureSkipVerify
+
+	if ctx.Credential != "" {
+		cred, found := c.Credential(ctx.Credential)
+		if !found {
+			return conf, fmt.Errorf("credential %q of context %q not found", ctx.Credential, name)
+		}
+		conf.AuthType = cred.AuthType
+		conf.Username = cred.Username
+		conf.Password = cred.Password
+		conf.Token = cred.Token
+	}
+
+	return conf, nil
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufmconfig_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/openbce/kperf/pkg/ufm"
+	"github.com/openbce/kperf/pkg/ufm/ufmconfig"
+)
+
+func newConfig() *ufmconfig.Config {
+	conf := &ufmconfig.Config{}
+	conf.SetContext("staging", ufmconfig.Context{Address: "ufm-staging.example.com", Credential: "staging-admin", CertificateAuthority: "/etc/ufm/staging-ca.pem"})
+	conf.SetContext("production", ufmconfig.Context{Address: "ufm-prod.example.com", Port: 8443, Schema: "https", Credential: "prod-token"})
+	conf.SetCredential("staging-admin", ufmconfig.Credential{Username: "admin", Password: "123456"})
+	conf.SetCredential("prod-token", ufmconfig.Credential{AuthType: "token", Token: "abc"})
+	conf.CurrentContext = "staging"
+
+	return conf
+}
+
+func TestUFMConfig(t *testing.T) {
+	staging := ufm.UFMConfig{Address: "ufm-staging.example.com", CertificateFile: "/etc/ufm/staging-ca.pem", Username: "admin", Password: "123456"}
+	production := ufm.UFMConfig{Address: "ufm-prod.example.com", Port: 8443, HTTPSchema: "https", AuthType: "token", Token: "abc"}
+
+	tests := []struct {
+		name    string
+		current string
+		context string
+		want    ufm.UFMConfig
+		wantErr bool
+	}{
+		{name: "current context", current: "staging", want: staging},
+		{name: "named context", current: "staging", context: "production", want: production},
+		{name: "no context", want: ufm.UFMConfig{}},
+		{name: "unknown context", current: "staging", context: "dev", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := newConfig()
+			conf.CurrentContext = tt.current
+
+			got, err := conf.UFMConfig(tt.context)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error of context %q", tt.context)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to build UFMConfig: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, expected %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUFMConfigMissingCredential(t *testing.T) {
+	conf := newConfig()
+	conf.SetContext("dev", ufmconfig.Context{Address: "ufm-dev.example.com", Credential: "dev-admin"})
+
+	if _, err := conf.UFMConfig("dev"); err == nil {
+		t.Errorf("expected error of the missing credential")
+	}
+}
+
+func TestEnvOverride(t *testing.T) {
+	t.Setenv("UFM_ADDRESS", "ufm-override.example.com")
+	t.Setenv("UFM_PASSWORD", "654321")
+
+	conf, err := newConfig().UFMConfig("staging")
+	if err != nil {
+		t.Fatalf("failed to build UFMConfig: %v", err)
+	}
+	if err := conf.OverrideFromEnv(); err != nil {
+		t.Fatalf("failed to override by env: %v", err)
+	}
+
+	// The environment values take precedence; the fields without them are kept.
+	want := ufm.UFMConfig{Address: "ufm-override.example.com", CertificateFile: "/etc/ufm/staging-ca.pem", Username: "admin", Password: "654321"}
+	if !reflect.DeepEqual(conf, want) {
+		t.Errorf("got %+v, expected %+v", conf, want)
+	}
+}
+
+func TestUseContext(t *testing.T) {
+	conf := newConfig()
+
+	if err := conf.UseContext("production"); err != nil || conf.CurrentContext != "production" {
+		t.Errorf("failed to use context production: %v", err)
+	}
+	if err := conf.UseContext("dev"); err == nil || conf.CurrentContext != "production" {
+		t.Errorf("unknown context dev is used: %v", err)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".ufm", "config")
+	t.Setenv(ufmconfig.EnvConfigPath, path)
+
+	if p, err := ufmconfig.DefaultPath(); err != nil || p != path {
+		t.Fatalf("default path is %q, %v, expected %q", p, err, path)
+	}
+
+	empty, err := ufmconfig.Load(path)
+	if err != nil {
+		t.Fatalf("failed to load the missing config file: %v", err)
+	}
+	if !reflect.DeepEqual(empty, &ufmconfig.Config{}) {
+		t.Errorf("got %+v from the missing config file, expected an empty config", empty)
+	}
+
+	conf := newConfig()
+	if err := conf.Save(path); err != nil {
+		t.Fatalf("failed to save config: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("failed to stat config file: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("config file is %v, expected only accessible by the owner", perm)
+	}
+
+	loaded, err := ufmconfig.Load(path)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if !reflect.DeepEqual(loaded, conf) {
+		t.Errorf("loaded %+v, expected %+v", loaded, conf)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte("current-context: staging\nunknown: true\n"), 0600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	if _, err := ufmconfig.Load(path); err == nil {
+		t.Errorf("expected error of the unknown field")
+	}
+}
@@ -0,0 +1,650 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufmtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openbce/kperf/pkg/ufm"
+)
+
+const (
+	pkeysPath       = "/ufmRest/resources/pkeys"
+	qosPath         = "/ufmRest/resources/pkeys/qos_conf"
+	removeGUIDsPath = "/ufmRest/actions/remove_guids_from_pkey"
+	portsPath       = "/ufmRest/resources/ports"
+	versionPath     = "/ufmRest/app/ufm_version"
+	sharpPath       = "/ufmRest/app/sharp/reservations"
+	systemsPath     = "/ufmRest/resources/systems"
+	linksPath       = "/ufmRest/resources/links"
+	eventsPath      = "/ufmRest/app/events"
+	alarmsPath      = "/ufmRest/app/alarms"
+	monitoringPath  = "/ufmRest/monitoring/start"
+	sessionPath     = "/ufmRest/monitoring/session"
+	actionsPath     = "/ufmRest/actions"
+	jobsPath        = "/ufmRest/jobs"
+)
+
+type qosConf struct {
+	ServiceLevel int32   `json:"service_level"`
+	MTU          int32   `json:"mtu_limit"`
+	RateLimit    float64 `json:"rate_limit"`
+}
+
+type guidData struct {
+	GUID       string `json:"guid"`
+	Index0     bool   `json:"index0"`
+	Membership string `json:"membership"`
+}
+
+type sharpData struct {
+	PKey  string   `json:"pkey"`
+	GUIDs []string `json:"guids"`
+}
+
+// jobData is a job of UFM; it's running until polled twice.
+type jobData struct {
+	ID       string `json:"ID"`
+	Status   string `json:"Status"`
+	Progress int32  `json:"Progress"`
+	Summary  string `json:"Summary"`
+
+	polls int
+	err   string
+}
+
+type pkeyData struct {
+	Partition string      `json:"partition"`
+	IPoIB     bool        `json:"ip_over_ib"`
+	Qos       *qosConf    `json:"qos_conf,omitempty"`
+	GUIDs     []*guidData `json:"guids,omitempty"`
+}
+
+func (s *Server) route(w http.ResponseWriter, r *http.Request, path string) {
+	switch {
+	case path == versionPath && r.Method == http.MethodGet:
+		s.getVersion(w)
+	case path == qosPath && r.Method == http.MethodPut:
+		s.updateQoS(w, r)
+	case path == pkeysPath && r.Method == http.MethodGet:
+		s.listPKeys(w, r)
+	case path == pkeysPath && r.Method == http.MethodPost:
+		s.addGUIDs(w, r)
+	case strings.HasPrefix(path, pkeysPath+"/") && r.Method == http.MethodGet:
+		s.getPKey(w, r, strings.TrimPrefix(path, pkeysPath+"/"))
+	case strings.HasPrefix(path, pkeysPath+"/") && r.Method == http.MethodDelete:
+		s.deletePKey(w, strings.TrimPrefix(path, pkeysPath+"/"))
+	case path == removeGUIDsPath && r.Method == http.MethodPost:
+		s.removeGUIDs(w, r)
+	case path == portsPath && r.Method == http.MethodGet:
+		s.listPorts(w, r)
+	case path == sharpPath && r.Method == http.MethodGet:
+		s.listSharp(w)
+	case path == sharpPath && r.Method == http.MethodPost:
+		s.createSharp(w, r)
+	case strings.HasPrefix(path, sharpPath+"/") && r.Method == http.MethodGet:
+		s.getSharp(w, strings.TrimPrefix(path, sharpPath+"/"))
+	case strings.HasPrefix(path, sharpPath+"/") && r.Method == http.MethodDelete:
+		s.deleteSharp(w, strings.TrimPrefix(path, sharpPath+"/"))
+	case path == systemsPath && r.Method == http.MethodGet:
+		s.listSystems(w, "")
+	case strings.HasPrefix(path, systemsPath+"/") && r.Method == http.MethodGet:
+		s.listSystems(w, strings.TrimPrefix(path, systemsPath+"/"))
+	case path == linksPath && r.Method == http.MethodGet:
+		s.listLinks(w)
+	case path == eventsPath && r.Method == http.MethodGet:
+		s.listEvents(w)
+	case path == alarmsPath && r.Method == http.MethodGet:
+		s.listAlarms(w)
+	case path == monitoringPath && r.Method == http.MethodPost:
+		s.startMonitoring(w, r)
+	case strings.HasPrefix(path, sessionPath+"/") && strings.HasSuffix(path, "/data") && r.Method == http.MethodGet:
+		s.getMonitoringData(w, strings.TrimSuffix(strings.TrimPrefix(path, sessionPath+"/"), "/data"))
+	case strings.HasPrefix(path, sessionPath+"/") && r.Method == http.MethodDelete:
+		s.deleteMonitoring(w, strings.TrimPrefix(path, sessionPath+"/"))
+	case path == actionsPath && r.Method == http.MethodPost:
+		s.runAction(w, r)
+	case strings.HasPrefix(path, jobsPath+"/") && r.Method == http.MethodGet:
+		s.getJob(w, strings.TrimPrefix(path, jobsPath+"/"))
+	default:
+		writeError(w, http.StatusNotFound, fmt.Sprintf("%s %s not found", r.Method, path))
+	}
+}
+
+func (s *Server) getVersion(w http.ResponseWriter) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	writeJSON(w, map[string]string{"ufm_release_version": s.version})
+}
+
+func (s *Server) listPKeys(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	withQoS, withGUIDs := r.URL.Query().Get("qos_conf") == "true", r.URL.Query().Get("guids_data") == "true"
+	res := map[string]*pkeyData{}
+	for pkey, p := range s.pkeys {
+		res[fmt.Sprintf("0x%x", pkey)] = p.toData(withQoS, withGUIDs)
+	}
+
+	writeJSON(w, res)
+}
+
+func (s *Server) getPKey(w http.ResponseWriter, r *http.Request, pkeyStr string) {
+	pkey, err := ufm.ParsePkey(pkeyStr)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid pkey %s", pkeyStr))
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	p, found := s.pkeys[pkey]
+	if !found {
+		// UFM replies an empty object for the pkey not found.
+		writeJSON(w, map[string]interface{}{})
+		return
+	}
+
+	withQoS, withGUIDs := r.URL.Query().Get("qos_conf") == "true", r.URL.Query().Get("guids_data") == "true"
+	writeJSON(w, p.toData(withQoS, withGUIDs))
+}
+
+func (s *Server) addGUIDs(w http.ResponseWriter, r *http.Request) {
+	req := struct {
+		PKey       string   `json:"pkey"`
+		Name       string   `json:"partition"`
+		IPoIB      bool     `json:"ip_over_ib"`
+		Index0     bool     `json:"index0"`
+		GUIDs      []string `json:"guids"`
+		Membership string   `json:"membership"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	pkey, err := ufm.ParsePkey(req.PKey)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid pkey %s", req.PKey))
+		return
+	}
+	if req.Membership == "" {
+		req.Membership = "full"
+	}
+	if req.Membership != "full" && req.Membership != "limited" {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid membership %s", req.Membership))
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	p, found := s.pkeys[pkey]
+	if !found {
+		p = &partition{
+			name:      req.Name,
+			mtu:       2,
+			rateLimit: 2.5,
+			guids:     map[string]*member{},
+		}
+		s.pkeys[pkey] = p
+	}
+	if req.Name != "" {
+		p.name = req.Name
+	}
+	p.ipoib = req.IPoIB
+	for _, g := range req.GUIDs {
+		p.guids[g] = &member{index0: req.Index0, membership: req.Membership}
+	}
+
+	if s.asyncGUIDs > 0 && len(req.GUIDs) >= s.asyncGUIDs {
+		s.acceptJob(w, false)
+		return
+	}
+	writeJSON(w, map[string]interface{}{})
+}
+
+func (s *Server) removeGUIDs(w http.ResponseWriter, r *http.Request) {
+	req := struct {
+		PKey  string   `json:"pkey"`
+		GUIDs []string `json:"guids"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	pkey, err := ufm.ParsePkey(req.PKey)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid pkey %s", req.PKey))
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	p, found := s.pkeys[pkey]
+	if !found {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("pkey %s not found", req.PKey))
+		return
+	}
+	for _, g := range req.GUIDs {
+		delete(p.guids, g)
+	}
+
+	if s.asyncGUIDs > 0 && len(req.GUIDs) >= s.asyncGUIDs {
+		s.acceptJob(w, false)
+		return
+	}
+	writeJSON(w, map[string]interface{}{})
+}
+
+func (s *Server) updateQoS(w http.ResponseWriter, r *http.Request) {
+	req := struct {
+		PKey string `json:"pkey"`
+		qosConf
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	pkey, err := ufm.ParsePkey(req.PKey)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid pkey %s", req.PKey))
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	p, found := s.pkeys[pkey]
+	if !found {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("pkey %s not found", req.PKey))
+		return
+	}
+	p.serviceLevel = req.ServiceLevel
+	p.mtu = req.MTU
+	p.rateLimit = req.RateLimit
+
+	writeJSON(w, map[string]interface{}{})
+}
+
+func (s *Server) deletePKey(w http.ResponseWriter, pkeyStr string) {
+	pkey, err := ufm.ParsePkey(pkeyStr)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid pkey %s", pkeyStr))
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, found := s.pkeys[pkey]; !found {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("pkey %s not found", pkeyStr))
+		return
+	}
+	delete(s.pkeys, pkey)
+
+	writeJSON(w, map[string]interface{}{})
+}
+
+func (s *Server) listPorts(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	sysType, system := r.URL.Query().Get("sys_type"), r.URL.Query().Get("system")
+	res := []ufm.IBPort{}
+	for _, p := range s.ports {
+		if sysType != "" && !strings.EqualFold(sysType, p.systemType) {
+			continue
+		}
+		if system != "" && !strings.EqualFold(system, p.SystemName) {
+			continue
+		}
+		res = append(res, p.IBPort)
+	}
+
+	writeJSON(w, res)
+}
+
+func (s *Server) listSharp(w http.ResponseWriter) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	res := []*sharpData{}
+	for pkey, guids := range s.sharp {
+		res = append(res, &sharpData{PKey: fmt.Sprintf("0x%x", pkey), GUIDs: guids})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].PKey < res[j].PKey })
+
+	writeJSON(w, res)
+}
+
+func (s *Server) getSharp(w http.ResponseWriter, pkeyStr string) {
+	pkey, err := ufm.ParsePkey(pkeyStr)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid pkey %s", pkeyStr))
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	guids, found := s.sharp[pkey]
+	if !found {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("SHARP reservation of pkey %s not found", pkeyStr))
+		return
+	}
+
+	writeJSON(w, &sharpData{PKey: fmt.Sprintf("0x%x", pkey), GUIDs: guids})
+}
+
+func (s *Server) createSharp(w http.ResponseWriter, r *http.Request) {
+	req := sharpData{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	pkey, err := ufm.ParsePkey(req.PKey)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid pkey %s", req.PKey))
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, found := s.pkeys[pkey]; !found {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("pkey %s not found", req.PKey))
+		return
+	}
+	if _, found := s.sharp[pkey]; found {
+		writeError(w, http.StatusConflict, fmt.Sprintf("SHARP reservation of pkey %s exists", req.PKey))
+		return
+	}
+	s.sharp[pkey] = req.GUIDs
+
+	writeJSON(w, map[string]interface{}{})
+}
+
+func (s *Server) deleteSharp(w http.ResponseWriter, pkeyStr string) {
+	pkey, err := ufm.ParsePkey(pkeyStr)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid pkey %s", pkeyStr))
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, found := s.sharp[pkey]; !found {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("SHARP reservation of pkey %s not found", pkeyStr))
+		return
+	}
+	delete(s.sharp, pkey)
+
+	writeJSON(w, map[string]interface{}{})
+}
+
+// listSystems returns the systems, or the system of the name in a list as UFM does.
+func (s *Server) listSystems(w http.ResponseWriter, name string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	res := []ufm.IBSystem{}
+	for _, sys := range s.systems {
+		if name != "" && name != sys.Name {
+			continue
+		}
+		item := *sys
+		if len(item.Ports) == 0 {
+			for _, p := range s.ports {
+				if p.SystemID == sys.Name {
+					item.Ports = append(item.Ports, p.Name)
+				}
+			}
+		}
+		res = append(res, item)
+	}
+
+	writeJSON(w, res)
+}
+
+func (s *Server) listLinks(w http.ResponseWriter) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	res := []ufm.IBLink{}
+	for _, l := range s.links {
+		res = append(res, *l)
+	}
+
+	writeJSON(w, res)
+}
+
+func (s *Server) listEvents(w http.ResponseWriter) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	res := []ufm.IBEvent{}
+	for _, e := range s.events {
+		res = append(res, *e)
+	}
+
+	writeJSON(w, res)
+}
+
+func (s *Server) listAlarms(w http.ResponseWriter) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	res := []ufm.IBAlarm{}
+	for _, a := range s.alarms {
+		res = append(res, *a)
+	}
+
+	writeJSON(w, res)
+}
+
+// startMonitoring accepts the monitoring session by 202 with its path in Location.
+func (s *Server) startMonitoring(w http.ResponseWriter, r *http.Request) {
+	req := struct {
+		Ports []string `json:"ports"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(req.Ports) == 0 {
+		writeError(w, http.StatusBadRequest, "no ports to monitor")
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.nextID++
+	id := strconv.Itoa(s.nextID)
+	s.monitors[id] = req.Ports
+
+	w.Header().Set("Location", sessionPath+"/"+id)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) getMonitoringData(w http.ResponseWriter, id string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	ports, found := s.monitors[id]
+	if !found {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("monitoring session %s not found", id))
+		return
+	}
+
+	res := map[string]interface{}{"last_updated": time.Now().UTC().Format(time.RFC3339Nano)}
+	for _, p := range ports {
+		res[p] = s.counters[p]
+	}
+
+	writeJSON(w, res)
+}
+
+func (s *Server) deleteMonitoring(w http.ResponseWriter, id string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, found := s.monitors[id]; !found {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("monitoring session %s not found", id))
+		return
+	}
+	delete(s.monitors, id)
+
+	writeJSON(w, map[string]interface{}{})
+}
+
+// runAction applies the port action at once, and accepts it by 202 with its job.
+func (s *Server) runAction(w http.ResponseWriter, r *http.Request) {
+	req := struct {
+		Params struct {
+			PortID string `json:"port_id"`
+		} `json:"params"`
+		Action string `json:"action"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var target *port
+	for _, p := range s.ports {
+		if p.Name == req.Params.PortID {
+			target = p
+		}
+	}
+	if target == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("port %s not found", req.Params.PortID))
+		return
+	}
+
+	switch req.Action {
+	case "enable", "reset":
+		target.LogicalState, target.PhysicalState = "Active", "LinkUp"
+	case "disable":
+		target.LogicalState, target.PhysicalState = "Down", "Disabled"
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown action %s", req.Action))
+		return
+	}
+
+	s.acceptJob(w, true)
+}
+
+// acceptJob creates a job, and accepts the request by 202 with the job in Location as UFM
+// does; the body is {"id": <job id>} if withID, e.g. for the actions, otherwise empty. The
+// caller must hold the mutex.
+func (s *Server) acceptJob(w http.ResponseWriter, withID bool) {
+	s.nextID++
+	id := strconv.Itoa(s.nextID)
+	s.jobs[id] = &jobData{ID: id, Status: "Running", err: s.jobError}
+
+	w.Header().Set("Location", jobsPath+"/"+id)
+	if !withID {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(map[string]string{"id": id})
+}
+
+func (s *Server) getJob(w http.ResponseWriter, id string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	job, found := s.jobs[id]
+	if !found {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("job %s not found", id))
+		return
+	}
+
+	if job.polls++; job.polls >= 2 && job.Status == "Running" {
+		job.Status, job.Progress = "Completed", 100
+		if job.err != "" {
+			job.Status, job.Summary = "Completed With Errors", job.err
+		}
+	} else if job.Status == "Running" {
+		job.Progress = 50
+	}
+
+	writeJSON(w, job)
+}
+
+func (p *partition) toData(withQoS, withGUIDs bool) *pkeyData {
+	data := &pkeyData{
+		Partition: p.name,
+		IPoIB:     p.ipoib,
+	}
+	if withQoS {
+		data.Qos = &qosConf{
+			ServiceLevel: p.serviceLevel,
+			MTU:          p.mtu,
+			RateLimit:    p.rateLimit,
+		}
+	}
+	if withGUIDs {
+		data.GUIDs = []*guidData{}
+		for _, g := range sortedGUIDs(p.guids) {
+			data.GUIDs = append(data.GUIDs, &guidData{
+				GUID:       g,
+				Index0:     p.guids[g].index0,
+				Membership: p.guids[g].membership,
+			})
+		}
+	}
+
+	return data
+}
+
+func writeJSON(w http.ResponseWriter, obj interface{}) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
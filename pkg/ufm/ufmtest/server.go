@@ -0,0 +1,586 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ufmtest provides an in-process fake UFM REST server, so ufm.UFM and
+// the ufm command line can be tested end to end without a real UFM, e.g.
+//
+//	srv := ufmtest.NewServer()
+//	defer srv.Close()
+//	u, err := ufm.NewUFMWithConfig(srv.Config())
+package ufmtest
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openbce/kperf/pkg/ufm"
+)
+
+const (
+	DefaultUsername = "admin"
+	DefaultPassword = "123456"
+	DefaultToken    = "ufmtest-token"
+	DefaultVersion  = "6.13.0-ufmtest"
+
+	sessionCookie = "ufmtest_session"
+)
+
+// Fault is injected into the responses of the requests which match its Method and Path.
+type Fault struct {
+	// The HTTP method to match; empty matches all methods.
+	Method string
+	// The prefix of the path to match, e.g. "/ufmRest/resources/pkeys"; empty matches all
+	// paths. The path is matched after rewriting "/ufmRestV2" and "/ufmRestV3" to "/ufmRest".
+	Path string
+	// The delay before handling the request.
+	Latency time.Duration
+	// The status code of the response; the request is handled as usual if it's 0.
+	StatusCode int
+	// The body of the response with StatusCode.
+	Body string
+	// Reply a malformed JSON instead of the normal response.
+	MalformedJSON bool
+	// How many times the fault is injected; 0 means always.
+	Times int
+
+	hits int
+}
+
+// Request is a request recorded by the Server.
+type Request struct {
+	Method string
+	// The path after rewriting "/ufmRestV2" and "/ufmRestV3" to "/ufmRest".
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+}
+
+// Server is a fake UFM REST server with in-memory pkeys, GUIDs, SHARP reservations, ports, systems, links,
+// events, alarms, monitoring sessions and jobs.
+type Server struct {
+	*httptest.Server
+
+	Username string
+	Password string
+	Token    string
+
+	mutex      sync.Mutex
+	version    string
+	pkeys      map[int32]*partition
+	sharp      map[int32][]string
+	ports      []*port
+	systems    []*ufm.IBSystem
+	links      []*ufm.IBLink
+	events     []*ufm.IBEvent
+	alarms     []*ufm.IBAlarm
+	monitors   map[string][]string
+	counters   map[string]ufm.PortCounters
+	jobs       map[string]*jobData
+	jobError   string
+	asyncGUIDs int
+	nextID     int
+	faults     []*Fault
+	hooks      []*hook
+	requests   []Request
+	sessions   map[string]struct{}
+	sessionSeq int
+}
+
+// hook is called after the server handles the requests which match its method and path.
+type hook struct {
+	method string
+	path   string
+	fn     func(Request)
+}
+
+type partition struct {
+	name         string
+	ipoib        bool
+	serviceLevel int32
+	mtu          int32
+	rateLimit    float64
+	guids        map[string]*member
+}
+
+type member struct {
+	index0     bool
+	membership string
+}
+
+type port struct {
+	ufm.IBPort
+	systemType string
+}
+
+// NewServer starts a fake UFM server by http, which accepts DefaultUsername and
+// DefaultPassword for basic and session auth, and DefaultToken for token auth.
+// The default pkey 0x7fff is created without GUIDs.
+func NewServer() *Server {
+	s := newServer()
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// NewTLSServer starts a fake UFM server by https; the certificate of the server
+// can be got by Certificate().
+func NewTLSServer() *Server {
+	s := newServer()
+	s.Server = httptest.NewTLSServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+func newServer() *Server {
+	s := &Server{
+		Username: DefaultUsername,
+		Password: DefaultPassword,
+		Token:    DefaultToken,
+		version:  DefaultVersion,
+		pkeys:    map[int32]*partition{},
+		sharp:    map[int32][]string{},
+		sessions: map[string]struct{}{},
+		monitors: map[string][]string{},
+		counters: map[string]ufm.PortCounters{},
+		jobs:     map[string]*jobData{},
+	}
+	s.pkeys[ufm.DefaultPKey] = &partition{
+		name:      "management",
+		mtu:       2,
+		rateLimit: 2.5,
+		guids:     map[string]*member{},
+	}
+
+	return s
+}
+
+// Config returns the UFMConfig to connect to the server by basic auth.
+func (s *Server) Config() ufm.UFMConfig {
+	u, _ := url.Parse(s.URL)
+	host, portStr, _ := net.SplitHostPort(u.Host)
+	p, _ := strconv.Atoi(portStr)
+
+	return ufm.UFMConfig{
+		Username:   s.Username,
+		Password:   s.Password,
+		Address:    host,
+		Port:       p,
+		HTTPSchema: u.Scheme,
+		Insecure:   u.Scheme == "https",
+	}
+}
+
+// Env returns the environment values to connect to the server, e.g. for the ufm command line.
+func (s *Server) Env() []string {
+	conf := s.Config()
+	return []string{
+		"UFM_USERNAME=" + conf.Username,
+		"UFM_PASSWORD=" + conf.Password,
+		"UFM_ADDRESS=" + conf.Address,
+		fmt.Sprintf("UFM_PORT=%d", conf.Port),
+		"UFM_HTTP_SCHEMA=" + conf.HTTPSchema,
+		fmt.Sprintf("UFM_INSECURE_SKIP_VERIFY=%t", conf.Insecure),
+	}
+}
+
+// SetVersion sets the release version reported by the server.
+func (s *Server) SetVersion(ver string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.version = ver
+}
+
+// AddIBNetwork creates or replaces the pkey of the IB network, including its GUIDs and QoS.
+func (s *Server) AddIBNetwork(ib *ufm.IBNetwork) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	p := &partition{
+		name:         ib.Name,
+		ipoib:        ib.IPOverIB,
+		serviceLevel: ib.ServiceLevel,
+		mtu:          ufm.ParseMTU(ib.MTU),
+		rateLimit:    ib.RateLimit,
+		guids:        map[string]*member{},
+	}
+	for _, m := range ib.GUIDMembers() {
+		p.guids[m.GUID] = &member{index0: m.Index0, membership: string(m.Membership)}
+	}
+	s.pkeys[ib.PKey] = p
+	if ib.EnableSharp {
+		s.sharp[ib.PKey] = ib.GUIDs
+	}
+}
+
+// GetIBNetwork returns the IB network of the pkey in the server, so the tests can check
+// the result without ufm.UFM.
+func (s *Server) GetIBNetwork(pkey int32) (*ufm.IBNetwork, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	p, found := s.pkeys[pkey]
+	if !found {
+		return nil, false
+	}
+
+	ib := &ufm.IBNetwork{
+		Name:         p.name,
+		PKey:         pkey,
+		MTU:          p.mtu,
+		IPOverIB:     p.ipoib,
+		ServiceLevel: p.serviceLevel,
+		RateLimit:    p.rateLimit,
+	}
+	ib.Index0 = len(p.guids) != 0
+	for _, g := range sortedGUIDs(p.guids) {
+		ib.GUIDs = append(ib.GUIDs, g)
+		ib.Members = append(ib.Members, ufm.GUIDMember{
+			GUID:       g,
+			Membership: ufm.Membership(p.guids[g].membership),
+			Index0:     p.guids[g].index0,
+		})
+		ib.Index0 = ib.Index0 && p.guids[g].index0
+	}
+	_, ib.EnableSharp = s.sharp[pkey]
+
+	return ib, true
+}
+
+// SharpReservation returns the GUIDs of the SHARP reservation of the pkey.
+func (s *Server) SharpReservation(pkey int32) ([]string, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	guids, found := s.sharp[pkey]
+	return guids, found
+}
+
+// PKeys returns the pkeys in the server in order.
+func (s *Server) PKeys() []int32 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var res []int32
+	for k := range s.pkeys {
+		res = append(res, k)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+
+	return res
+}
+
+// AddPort adds a port of the system type, e.g. "Computer" or "Switch".
+func (s *Server) AddPort(p ufm.IBPort, systemType string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.ports = append(s.ports, &port{IBPort: p, systemType: systemType})
+}
+
+// AddSystem adds a system; the names of its ports are filled from the ports of the
+// system ID if not set.
+func (s *Server) AddSystem(sys ufm.IBSystem) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.systems = append(s.systems, &sys)
+}
+
+// AddLink adds a link between two ports; the ports are named "<system guid>_<port number>" in UFM.
+func (s *Server) AddLink(l ufm.IBLink) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.links = append(s.links, &l)
+}
+
+// AddEvent adds an event to the event log; the ID and Timestamp are set if empty.
+func (s *Server) AddEvent(e ufm.IBEvent) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if e.ID == 0 {
+		e.ID = int64(len(s.events) + 1)
+	}
+	if e.Timestamp == "" {
+		e.Timestamp = time.Now().UTC().Format("2006-01-02 15:04:05")
+	}
+	s.events = append(s.events, &e)
+}
+
+// AddAlarm adds an alarm; the ID and Timestamp are set if empty.
+func (s *Server) AddAlarm(a ufm.IBAlarm) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if a.ID == 0 {
+		a.ID = int64(len(s.alarms) + 1)
+	}
+	if a.Timestamp == "" {
+		a.Timestamp = time.Now().UTC().Format("2006-01-02 15:04:05")
+	}
+	s.alarms = append(s.alarms, &a)
+}
+
+// SetPortCounters sets the counters of the port name in the monitoring sessions; the counters
+// of the other ports are zero.
+func (s *Server) SetPortCounters(port string, c ufm.PortCounters) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.counters[port] = c
+}
+
+// MonitoringSession returns the ports of the monitoring session, and whether it exists.
+func (s *Server) MonitoringSession(id string) ([]string, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	ports, found := s.monitors[id]
+	return ports, found
+}
+
+// SetAsyncGUIDs makes the updates of at least n GUIDs of a pkey accepted by a job, as UFM does
+// for the large updates; the GUIDs are updated at once, and 0 disables the jobs.
+func (s *Server) SetAsyncGUIDs(n int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.asyncGUIDs = n
+}
+
+// FailJobs makes the following jobs complete with errors of the summary; the empty summary
+// makes them succeed again.
+func (s *Server) FailJobs(summary string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.jobError = summary
+}
+
+// Port returns the port of the name, e.g. to check its state after a port action.
+func (s *Server) Port(name string) (ufm.IBPort, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, p := range s.ports {
+		if p.Name == name {
+			return p.IBPort, true
+		}
+	}
+	return ufm.IBPort{}, false
+}
+
+// AddFault injects the fault into the following responses.
+func (s *Server) AddFault(f Fault) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.faults = append(s.faults, &f)
+}
+
+// AfterRequest calls fn after the server handles each request of the method and path, e.g. to
+// change the server as a concurrent client does before the response is sent; the empty method
+// matches all methods. fn is called without the lock, so it may call the methods of the server.
+func (s *Server) AfterRequest(method, path string, fn func(Request)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.hooks = append(s.hooks, &hook{method: method, path: path, fn: fn})
+}
+
+// ClearFaults removes all the injected faults.
+func (s *Server) ClearFaults() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.faults = nil
+}
+
+// Requests returns the requests received by the server in order.
+func (s *Server) Requests() []Request {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]Request(nil), s.requests...)
+}
+
+// ResetRequests clears the recorded requests.
+func (s *Server) ResetRequests() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.requests = nil
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	path, prefix := normalizePath(r.URL.Path)
+
+	req := Request{
+		Method: r.Method,
+		Path:   path,
+		Query:  r.URL.Query(),
+		Header: r.Header.Clone(),
+		Body:   body,
+	}
+
+	s.mutex.Lock()
+	s.requests = append(s.requests, req)
+	fault := s.matchFault(r.Method, path)
+	var hooks []*hook
+	for _, h := range s.hooks {
+		if (h.method == "" || strings.EqualFold(h.method, r.Method)) && h.path == path {
+			hooks = append(hooks, h)
+		}
+	}
+	s.mutex.Unlock()
+
+	if fault != nil && fault.Latency > 0 {
+		select {
+		case <-time.After(fault.Latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if path == "/dologin" {
+		s.login(w, r)
+		return
+	}
+
+	if !s.authenticate(r, prefix) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if fault != nil && fault.StatusCode != 0 {
+		w.WriteHeader(fault.StatusCode)
+		_, _ = w.Write([]byte(fault.Body))
+		return
+	}
+
+	if fault != nil && fault.MalformedJSON {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"malformed": `))
+		return
+	}
+
+	s.route(w, r, path)
+	for _, h := range hooks {
+		h.fn(req)
+	}
+}
+
+// matchFault returns the first fault matching the request; it should be called with the lock.
+func (s *Server) matchFault(method, path string) *Fault {
+	for _, f := range s.faults {
+		if f.Method != "" && !strings.EqualFold(f.Method, method) {
+			continue
+		}
+		if !strings.HasPrefix(path, f.Path) {
+			continue
+		}
+		if f.Times > 0 && f.hits >= f.Times {
+			continue
+		}
+		f.hits++
+		return f
+	}
+
+	return nil
+}
+
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if r.Form.Get("httpd_username") != s.Username || r.Form.Get("httpd_password") != s.Password {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	s.mutex.Lock()
+	// Number the sessions by a counter, so the expired ids are never valid again.
+	s.sessionSeq++
+	id := fmt.Sprintf("session-%d", s.sessionSeq)
+	s.sessions[id] = struct{}{}
+	s.mutex.Unlock()
+
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Value: id, Path: "/"})
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+// ExpireSessions invalidates all the sessions, so the clients have to login again.
+func (s *Server) ExpireSessions() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.sessions = map[string]struct{}{}
+}
+
+func (s *Server) authenticate(r *http.Request, prefix string) bool {
+	switch prefix {
+	case "/ufmRestV2":
+		c, err := r.Cookie(sessionCookie)
+		if err != nil {
+			return false
+		}
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		_, found := s.sessions[c.Value]
+		return found
+	case "/ufmRestV3":
+		return r.Header.Get("Authorization") == "Basic "+s.Token
+	default:
+		username, password, ok := r.BasicAuth()
+		return ok && username == s.Username && password == s.Password
+	}
+}
+
+// normalizePath rewrites the prefix of the REST API of session and token auth to "/ufmRest".
+func normalizePath(path string) (string, string) {
+	for _, prefix := range []string{"/ufmRestV2", "/ufmRestV3", "/ufmRest"} {
+		if strings.HasPrefix(path, prefix+"/") {
+			return "/ufmRest" + strings.TrimPrefix(path, prefix), prefix
+		}
+	}
+
+	return path, ""
+}
+
+func sortedGUIDs(guids map[string]*member) []string {
+	var res []string
+	for g := range guids {
+		res = append(res, g)
+	}
+	sort.Strings(res)
+
+	return res
+}
@@ -18,6 +18,7 @@ package ufm
 
 import (
 	"fmt"
+	"strings"
 )
 
 // IsPKeyValid check if the pkey is in the valid (15bits long)
@@ -49,21 +50,28 @@ func BuidPKey(pkey int32) (string, error) {
 
 func buildIBNetwork(pkey int32, param *PKey) *IBNetwork {
 	var guids []string
-	index0 := false
+	var members []GUIDMember
+	// The index0 of IB network is true only if all the GUIDs are stored at index 0.
+	index0 := len(param.GUIDs) != 0
 
 	for _, id := range param.GUIDs {
+		membership, err := ParseMembership(id.Membership)
+		if err != nil {
+			membership = FullMembership
+		}
 		guids = append(guids, id.GUID)
-		index0 = id.Index0
+		members = append(members, GUIDMember{GUID: id.GUID, Membership: membership, Index0: id.Index0})
+		index0 = index0 && id.Index0
 	}
 
 	return &IBNetwork{
 		Name:         param.Partition,
 		PKey:         pkey,
-		EnableSharp:  false,
 		GUIDs:        guids,
 		MTU:          ParseMTU(param.Qos.MTU),
 		IPOverIB:     param.IPoIB,
 		Index0:       index0,
+		Members:      members,
 		ServiceLevel: param.Qos.ServiceLevel,
 		RateLimit:    param.Qos.RateLimit,
 	}
@@ -103,3 +111,77 @@ func ParseMTU(mtu int32) int32 {
 		return 2
 	}
 }
+
+// ParseMembership parses the membership of GUID; empty means full.
+func ParseMembership(m string) (Membership, error) {
+	switch strings.ToLower(m) {
+	case "", string(FullMembership):
+		return FullMembership, nil
+	case string(LimitedMembership):
+		return LimitedMembership, nil
+	}
+
+	return "", fmt.Errorf("invalid membership %q, one of 'full' or 'limited'", m)
+}
+
+// ParseGUIDMember parses the GUID with optional membership, e.g. "0x0002c903000e0b72:limited".
+func ParseGUIDMember(s string) (GUIDMember, error) {
+	parts := strings.SplitN(s, ":", 2)
+	if parts[0] == "" {
+		return GUIDMember{}, fmt.Errorf("empty GUID in %q", s)
+	}
+
+	member := GUIDMember{GUID: parts[0], Membership: FullMembership}
+	if len(parts) == 2 {
+		membership, err := ParseMembership(parts[1])
+		if err != nil {
+			return GUIDMember{}, err
+		}
+		member.Membership = membership
+	}
+
+	return member, nil
+}
+
+// String formats the member as ParseGUIDMember, e.g. "0x0002c903000e0b72:limited"; the
+// membership is omitted for the full member.
+func (m GUIDMember) String() string {
+	if m.Membership == LimitedMembership {
+		return fmt.Sprintf("%s:%s", m.GUID, m.Membership)
+	}
+	return m.GUID
+}
+
+// GUIDMembers returns all the GUIDs of the IB network with their membership; the GUIDs
+// not in Members are full members with the Index0 of the IB network.
+func (ib *IBNetwork) GUIDMembers() []GUIDMember {
+	members := map[string]GUIDMember{}
+	for _, m := range ib.Members {
+		if m.Membership == "" {
+			m.Membership = FullMembership
+		}
+		members[normalizeGUID(m.GUID)] = m
+	}
+
+	var res []GUIDMember
+	seen := map[string]bool{}
+	for _, g := range ib.GUIDs {
+		if seen[normalizeGUID(g)] {
+			continue
+		}
+		seen[normalizeGUID(g)] = true
+		if m, found := members[normalizeGUID(g)]; found {
+			res = append(res, m)
+		} else {
+			res = append(res, GUIDMember{GUID: g, Membership: FullMembership, Index0: ib.Index0})
+		}
+	}
+	for _, m := range ib.Members {
+		if !seen[normalizeGUID(m.GUID)] {
+			seen[normalizeGUID(m.GUID)] = true
+			res = append(res, members[normalizeGUID(m.GUID)])
+		}
+	}
+
+	return res
+}
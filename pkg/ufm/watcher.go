@@ -0,0 +1,322 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventAdded    EventType = "ADDED"
+	EventModified EventType = "MODIFIED"
+	EventDeleted  EventType = "DELETED"
+	// EventSync re-delivers the cached partitions every ResyncPeriod, e.g. for the handlers
+	// to reconcile periodically.
+	EventSync EventType = "SYNC"
+)
+
+// EventKind is the kind of the object in the Event.
+type EventKind string
+
+const (
+	PartitionKind EventKind = "partition"
+	GUIDKind      EventKind = "guid"
+)
+
+// Event is a change of a partition, or of a GUID in the partition, found by the Watcher.
+type Event struct {
+	Type EventType `json:"type"`
+	Kind EventKind `json:"kind"`
+	PKey int32     `json:"pkey"`
+	// The time when the change is found.
+	Time time.Time `json:"time"`
+	// The partition after the change; the one before the change for EventDeleted.
+	IBNetwork *IBNetwork `json:"ib_network"`
+	// The partition before the change of the PartitionKind EventModified and EventSync.
+	Old *IBNetwork `json:"old,omitempty"`
+	// The GUID of the GUIDKind event.
+	Member *GUIDMember `json:"member,omitempty"`
+}
+
+// EventHandler handles the events of the Watcher; it's called in the goroutine of Run, so
+// it should not block for long.
+type EventHandler func(Event)
+
+// Watcher polls the partitions of UFM, keeps them in a local cache keyed by pkey, and
+// delivers the changes as events to the handlers, e.g.
+//
+//	w, err := u.NewWatcher(10 * time.Second)
+//	w.AddHandler(func(e ufm.Event) { ... })
+//	go w.Run(ctx)
+type Watcher struct {
+	ufm      *UFM
+	interval time.Duration
+
+	// ResyncPeriod re-delivers all the cached partitions as EventSync; 0 means no resync.
+	ResyncPeriod time.Duration
+	// OnError is called when the partitions are failed to list; the Watcher keeps polling.
+	OnError func(*UFMError)
+
+	mutex    sync.RWMutex
+	cache    map[int32]*IBNetwork
+	synced   bool
+	handlers []EventHandler
+	channels []chan Event
+	resync   chan struct{}
+}
+
+// NewWatcher creates a Watcher which lists the partitions of UFM every interval; the interval
+// must be positive.
+func (u *UFM) NewWatcher(interval time.Duration) (*Watcher, *UFMError) {
+	if interval <= 0 {
+		return nil, &UFMError{
+			Code:    InvalidConfigErr,
+			Message: fmt.Sprintf("invalid interval %v of the watcher, it must be positive", interval),
+		}
+	}
+
+	return &Watcher{
+		ufm:      u,
+		interval: interval,
+		cache:    map[int32]*IBNetwork{},
+		resync:   make(chan struct{}, 1),
+	}, nil
+}
+
+// AddHandler registers the handler of the events; it has to be called before Run.
+func (w *Watcher) AddHandler(handler EventHandler) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.handlers = append(w.handlers, handler)
+}
+
+// Events returns a channel of the events, which is closed when Run returns; it has to
+// be called before Run, and Run blocks if the channel is full until the context is done.
+func (w *Watcher) Events(buffer int) <-chan Event {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	ch := make(chan Event, buffer)
+	w.channels = append(w.channels, ch)
+	return ch
+}
+
+// Resync asks Run to list the partitions immediately instead of waiting for the interval.
+func (w *Watcher) Resync() {
+	select {
+	case w.resync <- struct{}{}:
+	default:
+	}
+}
+
+// HasSynced returns true if the partitions are listed at least once.
+func (w *Watcher) HasSynced() bool {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	return w.synced
+}
+
+// Get returns the cached partition of the pkey.
+func (w *Watcher) Get(pkey int32) (*IBNetwork, bool) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	ib, found := w.cache[pkey]
+	return ib, found
+}
+
+// List returns the cached partitions ordered by pkey.
+func (w *Watcher) List() []*IBNetwork {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	return sortedIBNetworks(w.cache)
+}
+
+// Run polls the partitions until the context is done; the first list delivers all the
+// partitions as EventAdded.
+func (w *Watcher) Run(ctx context.Context) {
+	defer func() {
+		w.mutex.Lock()
+		defer w.mutex.Unlock()
+		for _, ch := range w.channels {
+			close(ch)
+		}
+		w.channels = nil
+	}()
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	var resyncC <-chan time.Time
+	if w.ResyncPeriod > 0 {
+		resyncTicker := time.NewTicker(w.ResyncPeriod)
+		defer resyncTicker.Stop()
+		resyncC = resyncTicker.C
+	}
+
+	w.poll(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.poll(ctx)
+		case <-w.resync:
+			w.poll(ctx)
+		case <-resyncC:
+			now := time.Now().UTC()
+			for _, ib := range w.List() {
+				if !w.dispatch(ctx, Event{Type: EventSync, Kind: PartitionKind, PKey: ib.PKey, Time: now, IBNetwork: ib, Old: ib}) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func (w *Watcher) poll(ctx context.Context) {
+	ibs, ufmErr := w.ufm.ListIBNetworkWithContext(ctx)
+	if ufmErr != nil {
+		// The error is expected when the watcher is stopped.
+		if ctx.Err() == nil && w.OnError != nil {
+			w.OnError(ufmErr)
+		}
+		return
+	}
+
+	cur := map[int32]*IBNetwork{}
+	for _, ib := range ibs {
+		cur[ib.PKey] = ib
+	}
+
+	w.mutex.Lock()
+	old := w.cache
+	w.cache = cur
+	w.synced = true
+	w.mutex.Unlock()
+
+	now := time.Now().UTC()
+	for _, e := range buildEvents(old, cur) {
+		e.Time = now
+		if !w.dispatch(ctx, e) {
+			return
+		}
+	}
+}
+
+// dispatch delivers the event to the handlers and channels; it returns false if the context is
+// done while waiting for a full channel.
+func (w *Watcher) dispatch(ctx context.Context, e Event) bool {
+	w.mutex.RLock()
+	handlers, channels := w.handlers, w.channels
+	w.mutex.RUnlock()
+
+	for _, h := range handlers {
+		h(e)
+	}
+	for _, ch := range channels {
+		select {
+		case ch <- e:
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	return true
+}
+
+// buildEvents compares the partitions and returns the events ordered by pkey; the GUID
+// events follow the event of their partition.
+func buildEvents(old, cur map[int32]*IBNetwork) []Event {
+	var res []Event
+	for _, ib := range sortedIBNetworks(cur) {
+		prev, found := old[ib.PKey]
+		if !found {
+			res = append(res, Event{Type: EventAdded, Kind: PartitionKind, PKey: ib.PKey, IBNetwork: ib})
+			res = append(res, guidEvents(ib, nil, ib)...)
+			continue
+		}
+		if diffIBNetwork(prev, ib) != nil {
+			res = append(res, Event{Type: EventModified, Kind: PartitionKind, PKey: ib.PKey, IBNetwork: ib, Old: prev})
+			res = append(res, guidEvents(ib, prev, ib)...)
+		}
+	}
+	for _, ib := range sortedIBNetworks(old) {
+		if _, found := cur[ib.PKey]; !found {
+			res = append(res, Event{Type: EventDeleted, Kind: PartitionKind, PKey: ib.PKey, IBNetwork: ib})
+			res = append(res, guidEvents(ib, ib, nil)...)
+		}
+	}
+
+	return res
+}
+
+// guidEvents returns the events of the GUIDs changed from prev to cur; ib is the partition
+// of the events, and prev or cur is nil if the partition is added or deleted.
+func guidEvents(ib, prev, cur *IBNetwork) []Event {
+	prevMembers := map[string]GUIDMember{}
+	if prev != nil {
+		for _, m := range prev.GUIDMembers() {
+			prevMembers[normalizeGUID(m.GUID)] = m
+		}
+	}
+	curMembers := map[string]bool{}
+
+	var res []Event
+	if cur != nil {
+		for _, m := range cur.GUIDMembers() {
+			m := m
+			curMembers[normalizeGUID(m.GUID)] = true
+			p, found := prevMembers[normalizeGUID(m.GUID)]
+			switch {
+			case !found:
+				res = append(res, Event{Type: EventAdded, Kind: GUIDKind, PKey: ib.PKey, IBNetwork: ib, Member: &m})
+			case p.Membership != m.Membership || p.Index0 != m.Index0:
+				res = append(res, Event{Type: EventModified, Kind: GUIDKind, PKey: ib.PKey, IBNetwork: ib, Member: &m})
+			}
+		}
+	}
+	if prev != nil {
+		for _, m := range prev.GUIDMembers() {
+			m := m
+			if !curMembers[normalizeGUID(m.GUID)] {
+				res = append(res, Event{Type: EventDeleted, Kind: GUIDKind, PKey: ib.PKey, IBNetwork: ib, Member: &m})
+			}
+		}
+	}
+
+	return res
+}
+
+func sortedIBNetworks(ibs map[int32]*IBNetwork) []*IBNetwork {
+	var res []*IBNetwork
+	for _, ib := range ibs {
+		res = append(res, ib)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].PKey < res[j].PKey })
+
+	return res
+}
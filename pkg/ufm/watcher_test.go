@@ -0,0 +1,102 @@
+/*
+Copyright 2023 The openBCE Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufm_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/openbce/kperf/pkg/ufm"
+)
+
+func newWatcher(t *testing.T, u *ufm.UFM) *ufm.Watcher {
+	t.Helper()
+
+	w, err := u.NewWatcher(10 * time.Millisecond)
+	if err != nil {
+		t.Fatalf("failed to create watcher: %v", err)
+	}
+	return w
+}
+
+func TestWatcherEvents(t *testing.T) {
+	u, srv := newUFM(t, "")
+
+	w := newWatcher(t, u)
+	events := w.Events(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	next := func() ufm.Event {
+		select {
+		case e := <-events:
+			return e
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no event in time")
+		}
+		return ufm.Event{}
+	}
+
+	if e := next(); e.Type != ufm.EventAdded || e.PKey != ufm.DefaultPKey {
+		t.Fatalf("unexpected first event %+v", e)
+	}
+
+	srv.AddIBNetwork(&ufm.IBNetwork{PKey: 0x100, GUIDs: []string{guid1}})
+	if e := next(); e.Type != ufm.EventAdded || e.Kind != ufm.PartitionKind || e.PKey != 0x100 {
+		t.Errorf("unexpected partition event %+v", e)
+	}
+	if e := next(); e.Type != ufm.EventAdded || e.Kind != ufm.GUIDKind || e.Member == nil || e.Member.GUID != guid1 {
+		t.Errorf("unexpected GUID event %+v", e)
+	}
+}
+
+func TestWatcherStopsWithFullChannel(t *testing.T) {
+	u, srv := newUFM(t, "")
+	srv.AddIBNetwork(&ufm.IBNetwork{PKey: 0x100})
+
+	// The events are never received, so Run blocks on the full channel.
+	w := newWatcher(t, u)
+	_ = w.Events(0)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		w.Run(ctx)
+		close(done)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Run does not return after the context is done")
+	}
+}
+
+func TestWatcherInvalidInterval(t *testing.T) {
+	u, _ := newUFM(t, "")
+
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if _, err := u.NewWatcher(interval); err == nil || !errors.Is(err, ufm.ErrInvalidConfig) {
+			t.Errorf("expected invalid config error of interval %v, got %v", interval, err)
+		}
+	}
+}